@@ -0,0 +1,171 @@
+package main
+
+/*
+ * awsauth.go
+ * Sign requests to AWS
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// EMPTYSHA256 is the hex-encoded SHA256 hash of an empty payload
+	EMPTYSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// AMZDATEFORMAT is the format of the X-Amz-Date header
+	AMZDATEFORMAT = "20060102T150405Z"
+)
+
+/* awsCreds are the credentials used to sign requests to AWS. */
+type awsCreds struct {
+	id     string
+	secret string
+	token  string
+}
+
+/* awsCredsFromEnv gets AWS credentials from the standard AWS_ACCESS_KEY_ID,
+AWS_SECRET_ACCESS_KEY, and optional AWS_SESSION_TOKEN environment variables. */
+func awsCredsFromEnv() (*awsCreds, error) {
+	c := &awsCreds{
+		id:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secret: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:  os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if "" == c.id || "" == c.secret {
+		return nil, errors.New(
+			"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must " +
+				"be set",
+		)
+	}
+	return c, nil
+}
+
+/* sign adds an AWS Signature Version 4 Authorization header to req, whose
+body is payload, for the S3 service in the given region.  All of the headers
+already set on req are signed. */
+func (a *awsCreds) sign(req *http.Request, region string, payload []byte) {
+	now := time.Now().UTC()
+	day := now.Format("20060102")
+	ph := EMPTYSHA256
+	if 0 != len(payload) {
+		ph = sha256Hex(payload)
+	}
+
+	/* Headers AWS wants for every request */
+	req.Header.Set("X-Amz-Date", now.Format(AMZDATEFORMAT))
+	req.Header.Set("X-Amz-Content-Sha256", ph)
+	if "" != a.token {
+		req.Header.Set("X-Amz-Security-Token", a.token)
+	}
+
+	/* Work out the headers to sign, which is all of them plus host */
+	host := req.Host
+	if "" == host {
+		host = req.URL.Host
+	}
+	hs := map[string]string{"host": host}
+	for k, vs := range req.Header {
+		hs[strings.ToLower(k)] = strings.TrimSpace(
+			strings.Join(vs, ","),
+		)
+	}
+	names := make([]string, 0, len(hs))
+	for k := range hs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var ch strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&ch, "%v:%v\n", k, hs[k])
+	}
+	signed := strings.Join(names, ";")
+
+	/* Canonical request */
+	path := req.URL.EscapedPath()
+	if "" == path {
+		path = "/"
+	}
+	creq := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		ch.String(),
+		signed,
+		ph,
+	}, "\n")
+
+	/* String to sign */
+	scope := day + "/" + region + "/s3/aws4_request"
+	sts := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(AMZDATEFORMAT),
+		scope,
+		sha256Hex([]byte(creq)),
+	}, "\n")
+
+	/* Signing key and signature */
+	k := hmacSHA256([]byte("AWS4"+a.secret), day)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, sts))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, "+
+			"Signature=%v",
+		a.id,
+		scope,
+		signed,
+		sig,
+	))
+}
+
+/* canonicalQuery returns q encoded the way AWS wants for signing. */
+func canonicalQuery(q url.Values) string {
+	ks := make([]string, 0, len(q))
+	for k := range q {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	var ps []string
+	for _, k := range ks {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			ps = append(ps, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(ps, "&")
+}
+
+/* awsEscape percent-encodes everything but unreserved characters in s. */
+func awsEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+/* sha256Hex returns the hex-encoded SHA256 hash of b. */
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+/* hmacSHA256 returns the HMAC-SHA256 of s with key k. */
+func hmacSHA256(k []byte, s string) []byte {
+	h := hmac.New(sha256.New, k)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
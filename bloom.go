@@ -0,0 +1,149 @@
+package main
+
+/*
+ * bloom.go
+ * Remember names in bounded memory
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"sync"
+)
+
+const (
+	// BLOOMCAPACITY is the number of names the first Bloom filter holds
+	// before another, twice as large, is added
+	BLOOMCAPACITY = 1024 * 1024
+
+	// BLOOMFPRATE is the default probability of a name which hasn't been
+	// seen being treated as though it has
+	BLOOMFPRATE = 0.001
+
+	// BLOOMTIGHTEN is how much smaller each new filter's false positive
+	// rate is than the last's
+	BLOOMTIGHTEN = 0.5
+)
+
+/* bloomNames remembers names which have been processed in a scalable Bloom
+filter, which uses a bounded amount of memory per name and never forgets a
+name, but may think it's seen a name it hasn't.  Once a filter is full, another
+twice as large with a lower false positive rate is added, so the overall false
+positive rate stays under the rate asked for no matter how many names are
+added.  It is safe to call bloomNames' methods from multiple goroutines. */
+type bloomNames struct {
+	l      sync.Mutex
+	fs     []*bloomFilter
+	seeds  [2]maphash.Seed
+	nextFP float64
+}
+
+/* newBloomNames returns a bloomNames with an overall false positive rate of
+at most fp, which must be between 0 and 1. */
+func newBloomNames(fp float64) (*bloomNames, error) {
+	if 0 >= fp || 1 <= fp {
+		return nil, fmt.Errorf(
+			"false positive rate %v not between 0 and 1",
+			fp,
+		)
+	}
+	/* The filters' rates are a geometric series which sums to fp */
+	b := &bloomNames{
+		seeds:  [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		nextFP: fp * (1 - BLOOMTIGHTEN),
+	}
+	b.grow()
+	return b, nil
+}
+
+/* grow adds a new filter.  b.l must be held, if b's in use. */
+func (b *bloomNames) grow() {
+	c := uint64(BLOOMCAPACITY)
+	if 0 != len(b.fs) {
+		c = b.fs[len(b.fs)-1].capacity * 2
+	}
+	b.fs = append(b.fs, newBloomFilter(c, b.nextFP))
+	b.nextFP *= BLOOMTIGHTEN
+}
+
+/* hashes returns the two hashes used to find n's bits. */
+func (b *bloomNames) hashes(n string) (uint64, uint64) {
+	return maphash.String(b.seeds[0], n), maphash.String(b.seeds[1], n)
+}
+
+/* Seen returns true if n has been added, or, rarely, if it hasn't. */
+func (b *bloomNames) Seen(n string) bool {
+	h1, h2 := b.hashes(n)
+	b.l.Lock()
+	defer b.l.Unlock()
+	for _, f := range b.fs {
+		if f.Has(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+/* Add notes that n has been seen. */
+func (b *bloomNames) Add(n string) {
+	h1, h2 := b.hashes(n)
+	b.l.Lock()
+	defer b.l.Unlock()
+	f := b.fs[len(b.fs)-1]
+	if f.n >= f.capacity {
+		b.grow()
+		f = b.fs[len(b.fs)-1]
+	}
+	f.Add(h1, h2)
+}
+
+/* bloomFilter is a single fixed-size Bloom filter. */
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64 /* Number of bits */
+	k        uint64 /* Number of bits per name */
+	n        uint64 /* Names added */
+	capacity uint64 /* Names which can be added at the desired rate */
+}
+
+/* newBloomFilter returns a bloomFilter sized to hold capacity names with a
+false positive rate of fp. */
+func newBloomFilter(capacity uint64, fp float64) *bloomFilter {
+	m := uint64(math.Ceil(
+		-float64(capacity) * math.Log(fp) / (math.Ln2 * math.Ln2),
+	))
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if 0 == k {
+		k = 1
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+/* Has returns true if all of the bits for the hashes h1 and h2 are set. */
+func (f *bloomFilter) Has(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		b := (h1 + i*h2) % f.m
+		if 0 == f.bits[b/64]&(1<<(b%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Add sets the bits for the hashes h1 and h2. */
+func (f *bloomFilter) Add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		b := (h1 + i*h2) % f.m
+		f.bits[b/64] |= 1 << (b % 64)
+	}
+	f.n++
+}
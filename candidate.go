@@ -0,0 +1,90 @@
+package main
+
+/*
+ * candidate.go
+ * Possible bucket names and where they came from
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Candidate sources, describing how a possible bucket name was generated
+const (
+	SourceLiteral     = "literal"
+	SourceTagPrefix   = "tag-prefix"
+	SourceTagSuffix   = "tag-suffix"
+	SourceDateSuffix  = "date-suffix"
+	SourceDotSwap     = "dot-swap"
+	SourceParentLabel = "parent-label"
+	SourceWWWPrefix   = "www-prefix"
+	SourceCrtsh       = "crtsh-subdomain"
+	SourcePassiveDNS  = "passivedns-subdomain"
+	SourceScrape      = "scrape"
+)
+
+/* candidate is a possible bucket name, how it was generated, the input name
+from which it was generated, and the input's tracing span. */
+type candidate struct {
+	name   string
+	source string
+	input  string
+	span   trace.SpanContext
+}
+
+/* derive returns a candidate generated from the same input as c, with the
+given name and source. */
+func (c candidate) derive(name, source string) candidate {
+	return candidate{
+		name:   name,
+		source: source,
+		input:  c.input,
+		span:   c.span,
+	}
+}
+
+/* nameSources remembers which subdomain source found a name, until the name
+is processed.  A nil *nameSources remembers nothing.  It is safe to call
+nameSources' methods from multiple goroutines. */
+type nameSources struct {
+	l sync.Mutex
+	m map[string]string
+}
+
+/* newNameSources returns a new nameSources, or nil if enabled is false. */
+func newNameSources(enabled bool) *nameSources {
+	if !enabled {
+		return nil
+	}
+	return &nameSources{m: make(map[string]string)}
+}
+
+/* Set notes that the name n was found by src. */
+func (s *nameSources) Set(n, src string) {
+	if nil == s {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.m[n] = src
+}
+
+/* Take returns and forgets the source of n, or the empty string if n's
+source isn't known. */
+func (s *nameSources) Take(n string) string {
+	if nil == s {
+		return ""
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	src, ok := s.m[n]
+	if ok {
+		delete(s.m, n)
+	}
+	return src
+}
@@ -0,0 +1,87 @@
+package main
+
+/*
+ * certfile.go
+ * Get names from certificates in a file
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+/* namesFromCertFile sends the names in the file named n to c, the way
+watchLogs does for names from the certificate stream.  The file may either
+contain PEM certificates, in which case the names are the certificates'
+subject alternative names and common names, or be a CSV file in which every
+field containing a dot is taken to be one or more names separated by spaces
+or semicolons. */
+func namesFromCertFile(c chan<- string, n string) error {
+	b, err := ioutil.ReadFile(n)
+	if nil != err {
+		return err
+	}
+	if bytes.Contains(b, []byte("-----BEGIN")) {
+		return namesFromPEM(c, b)
+	}
+	return namesFromCSV(c, bytes.NewReader(b))
+}
+
+/* namesFromPEM sends the names from the PEM certificates in b to c.  Blocks
+which aren't certificates are skipped. */
+func namesFromPEM(c chan<- string, b []byte) error {
+	for {
+		var blk *pem.Block
+		blk, b = pem.Decode(b)
+		if nil == blk {
+			return nil
+		}
+		if "CERTIFICATE" != blk.Type {
+			continue
+		}
+		cert, err := x509.ParseCertificate(blk.Bytes)
+		if nil != err {
+			elog.Printf("Unable to parse certificate: %v", err)
+			continue
+		}
+		names := cert.DNSNames
+		if "" != cert.Subject.CommonName {
+			names = append(names, cert.Subject.CommonName)
+		}
+		sendCertNames(c, names)
+	}
+}
+
+/* namesFromCSV sends the names from the CSV in r to c. */
+func namesFromCSV(c chan<- string, r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	for {
+		rec, err := cr.Read()
+		if io.EOF == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+		for _, f := range rec {
+			if !strings.Contains(f, ".") {
+				continue
+			}
+			sendCertNames(c, strings.FieldsFunc(f, isNameSep))
+		}
+	}
+}
+
+/* isNameSep returns true if r separates names in a CSV field. */
+func isNameSep(r rune) bool {
+	return ';' == r || ' ' == r || '\t' == r
+}
@@ -0,0 +1,108 @@
+package main
+
+/*
+ * cloudfront.go
+ * Find buckets behind CloudFront
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CLOUDFRONTSUFFIX is the suffix of CloudFront distributions' names
+const CLOUDFRONTSUFFIX = ".cloudfront.net"
+
+/* getCloudFrontNames sends to out anything on ns, plus the names of S3
+buckets behind CloudFront for names on ns which point at CloudFront, if the
+bucket name can be determined.  Requests to CloudFront are made with c and S3
+origins are logged to slog. */
+func getCloudFrontNames(
+	out chan<- string,
+	ns <-chan string,
+	c *http.Client,
+	slog *log.Logger,
+) {
+	defer close(out)
+	for n := range ns {
+		/* Send out original name */
+		out <- n
+		/* Skip non-domains */
+		if !strings.Contains(n, ".") {
+			continue
+		}
+		/* Send out the bucket, if there is one */
+		b, err := cloudFrontBucket(n, c, slog)
+		if nil != err {
+			elog.Printf("[%v] CloudFront check error: %v", n, err)
+			continue
+		}
+		if "" != b {
+			out <- b
+		}
+	}
+}
+
+/* cloudFrontBucket checks if n is a CloudFront distribution with an S3
+origin, and if so logs it to slog and returns the name of the bucket, if
+CloudFront's response reveals it. */
+func cloudFrontBucket(
+	n string,
+	c *http.Client,
+	slog *log.Logger,
+) (string, error) {
+	/* Make sure it's CloudFront.  Lookup failures are really common with
+	names from certs and just mean it's not CloudFront. */
+	cname, err := net.LookupCNAME(n)
+	if nil != err {
+		return "", nil
+	}
+	cname = strings.TrimSuffix(cname, ".")
+	if !strings.HasSuffix(cname, CLOUDFRONTSUFFIX) {
+		return "", nil
+	}
+
+	/* See what's behind it */
+	res, err := c.Get("https://" + n + "/")
+	if nil != err {
+		return "", err
+	}
+	defer res.Body.Close()
+	if "AmazonS3" != res.Header.Get("Server") &&
+		"" == res.Header.Get("x-amz-bucket-region") {
+		return "", nil
+	}
+
+	/* S3's error document sometimes has the bucket name */
+	e := readS3Error(res.Body)
+	switch {
+	case "NoSuchBucket" == e.Code && "" != e.BucketName:
+		slog.Printf(
+			"[%v] CloudFront distribution %v has a nonexistent "+
+				"S3 origin bucket: %v",
+			n,
+			cname,
+			e.BucketName,
+		)
+	case "" != e.BucketName:
+		slog.Printf(
+			"[%v] CloudFront distribution %v has S3 origin "+
+				"bucket %v",
+			n,
+			cname,
+			e.BucketName,
+		)
+	default:
+		slog.Printf(
+			"[%v] CloudFront distribution %v has an S3 origin",
+			n,
+			cname,
+		)
+	}
+	return e.BucketName, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+/*
+ * company.go
+ * Generate names from a company name
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"strings"
+	"unicode"
+)
+
+/* CORPSUFFIXES are the words at the end of a company name which are dropped
+before making bucket names. */
+var CORPSUFFIXES = map[string]struct{}{
+	"co":           {},
+	"company":      {},
+	"corp":         {},
+	"corporation":  {},
+	"gmbh":         {},
+	"inc":          {},
+	"incorporated": {},
+	"limited":      {},
+	"llc":          {},
+	"ltd":          {},
+	"plc":          {},
+}
+
+/* COMPANYENVS are the environment-ish words companies put after their names,
+with and without a hyphen. */
+var COMPANYENVS = []string{
+	"prod",
+	"production",
+	"dev",
+	"staging",
+	"test",
+	"backup",
+	"backups",
+	"logs",
+	"data",
+}
+
+/* COMPANYDOTTED are the words companies put after their names, after a dot. */
+var COMPANYDOTTED = []string{
+	"assets",
+	"static",
+	"media",
+	"backup",
+}
+
+/* COMPANYREGIONS are the regions companies usually put after their names. */
+var COMPANYREGIONS = []string{
+	"us-east-1",
+	"us-east-2",
+	"us-west-1",
+	"us-west-2",
+	"eu-west-1",
+	"eu-central-1",
+	"ap-southeast-1",
+	"ap-northeast-1",
+}
+
+/* companyNames returns the names which might be used for buckets by the
+company named co, which makes the products in the comma-separated list prods
+and may have buckets in regions.  The company name is lowercased and split
+into words, less any trailing corporate suffixes like Inc or LLC.  The bases
+are then the words run together, joined with hyphens, and the first word
+alone.  For each base b,
+every env in COMPANYENVS, every word w in COMPANYDOTTED, every region r in
+regions, and every product p, the names are
+
+	b, b-env, benv, b.w, b-r, b-p
+
+and every product p on its own. */
+func companyNames(co, prods string, regions []string) []string {
+	/* Work out what the company's called */
+	ws := strings.FieldsFunc(strings.ToLower(co), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for 1 < len(ws) {
+		if _, ok := CORPSUFFIXES[ws[len(ws)-1]]; !ok {
+			break
+		}
+		ws = ws[:len(ws)-1]
+	}
+	if 0 == len(ws) {
+		return nil
+	}
+	bases := []string{strings.Join(ws, "")}
+	if 1 < len(ws) {
+		bases = append(bases, strings.Join(ws, "-"), ws[0])
+	}
+
+	/* Work out what it makes */
+	var ps []string
+	for _, p := range strings.Split(prods, ",") {
+		p = strings.Join(strings.Fields(strings.ToLower(p)), "-")
+		if "" != p {
+			ps = append(ps, p)
+		}
+	}
+
+	/* Roll the names */
+	var ns []string
+	for _, b := range bases {
+		ns = append(ns, b)
+		for _, e := range COMPANYENVS {
+			ns = append(ns, b+"-"+e, b+e)
+		}
+		for _, w := range COMPANYDOTTED {
+			ns = append(ns, b+"."+w)
+		}
+		for _, r := range regions {
+			ns = append(ns, b+"-"+r)
+		}
+		for _, p := range ps {
+			ns = append(ns, b+"-"+p)
+		}
+	}
+	return append(ns, ps...)
+}
@@ -0,0 +1,124 @@
+package main
+
+/*
+ * ctlstate.go
+ * Remember what crt.sh told us last time
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+)
+
+/* ctlValidators are the cache validators crt.sh sent for a query, and the
+names it sent with them.  Names is nil for state saved without names, which
+can't be used to skip a query. */
+type ctlValidators struct {
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Names        []string `json:"names"`
+}
+
+/* ctlState holds the cache validators and names for crt.sh queries between
+runs, so unchanged results needn't be fetched again.  A nil *ctlState
+remembers nothing.  It is safe to call ctlState's methods from multiple
+goroutines. */
+type ctlState struct {
+	l  sync.Mutex
+	fn string
+	m  map[string]ctlValidators
+}
+
+/* newCTLState returns a ctlState which is saved in the file named fn,
+loading the validators already in it, if it exists.  If fn is the empty
+string, newCTLState returns nil. */
+func newCTLState(fn string) (*ctlState, error) {
+	if "" == fn {
+		return nil, nil
+	}
+	s := &ctlState{fn: fn, m: make(map[string]ctlValidators)}
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&s.m); nil != err {
+		return nil, err
+	}
+	return s, nil
+}
+
+/* SetHeaders adds conditional request headers to req, for the query q, if
+we have validators and names for it. */
+func (s *ctlState) SetHeaders(req *http.Request, q string) {
+	if nil == s {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	v, ok := s.m[q]
+	if !ok || nil == v.Names {
+		return
+	}
+	if "" != v.ETag {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if "" != v.LastModified {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+/* Names returns the names saved for the query q, or nil if there aren't
+any. */
+func (s *ctlState) Names(q string) []string {
+	if nil == s {
+		return nil
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	return append([]string(nil), s.m[q].Names...)
+}
+
+/* Update saves the validators in res and the names sent with them, ns, for
+the query q. */
+func (s *ctlState) Update(q string, res *http.Response, ns []string) {
+	if nil == s {
+		return
+	}
+	v := ctlValidators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Names:        append([]string{}, ns...),
+	}
+	if "" == v.ETag && "" == v.LastModified {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.m[q] = v
+}
+
+/* Save writes the validators and names to the file. */
+func (s *ctlState) Save() error {
+	if nil == s {
+		return nil
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	f, err := os.Create(s.fn)
+	if nil != err {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(s.m); nil != err {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
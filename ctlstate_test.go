@@ -0,0 +1,101 @@
+package main
+
+/*
+ * ctlstate_test.go
+ * Tests for remembering what crt.sh told us last time
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+/* TestQueryCTLNotModified checks that subdomains crt.sh sent once are still
+returned, in this run and the next, once crt.sh says they haven't changed. */
+func TestQueryCTLNotModified(t *testing.T) {
+	const etag = `"v1"`
+	var nFull int
+	dc := http.DefaultClient
+	defer func() { http.DefaultClient = dc }()
+	http.DefaultClient = &http.Client{Transport: roundTripFunc(func(
+		req *http.Request,
+	) (*http.Response, error) {
+		res := &http.Response{
+			Header:  make(http.Header),
+			Body:    http.NoBody,
+			Request: req,
+		}
+		if etag == req.Header.Get("If-None-Match") {
+			res.StatusCode = http.StatusNotModified
+			return res, nil
+		}
+		nFull++
+		res.StatusCode = http.StatusOK
+		res.Header.Set("ETag", etag)
+		res.Body = io.NopCloser(strings.NewReader(
+			`[{"name_value":"a.example.com"},` +
+				`{"name_value":"b.example.com"}]`,
+		))
+		return res, nil
+	})}
+
+	fn := filepath.Join(t.TempDir(), "state")
+	want := []string{"a.example.com", "b.example.com"}
+	for run := 1; run <= 2; run++ {
+		s, err := newCTLState(fn)
+		if nil != err {
+			t.Fatalf("Run %v: loading state: %v", run, err)
+		}
+		for q := 1; q <= 2; q++ {
+			ns, err := queryCTL("example.com", CTLQUERY, 1<<20, s)
+			if nil != err {
+				t.Fatalf("Run %v query %v: %v", run, q, err)
+			}
+			sort.Strings(ns)
+			if !equalStrings(want, ns) {
+				t.Errorf(
+					"Run %v query %v: got %q, want %q",
+					run,
+					q,
+					ns,
+					want,
+				)
+			}
+		}
+		if err := s.Save(); nil != err {
+			t.Fatalf("Run %v: saving state: %v", run, err)
+		}
+	}
+	if 1 != nFull {
+		t.Errorf("crt.sh sent full results %v times, want 1", nFull)
+	}
+
+	/* Validators without names don't skip the query */
+	if err := os.WriteFile(
+		fn,
+		[]byte(`{"x":{"etag":"\"v1\""}}`),
+		0600,
+	); nil != err {
+		t.Fatalf("Writing old state: %v", err)
+	}
+	s, err := newCTLState(fn)
+	if nil != err {
+		t.Fatalf("Loading old state: %v", err)
+	}
+	req, err := http.NewRequest("GET", CTLURL+"x", nil)
+	if nil != err {
+		t.Fatalf("Making request: %v", err)
+	}
+	s.SetHeaders(req, "x")
+	if h := req.Header.Get("If-None-Match"); "" != h {
+		t.Errorf("Sent If-None-Match %q without saved names", h)
+	}
+}
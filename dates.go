@@ -0,0 +1,73 @@
+package main
+
+/*
+ * dates.go
+ * Date suffixes for names
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"time"
+)
+
+/* dateSuffixes returns the years and year-months, as YYYY and YYYYMM, from
+the month from to the month to, inclusive.  Both may be given either as YYYY
+or YYYY-MM; a year on its own is January for from and December for to.  If
+from is the empty string, it's January of last year; if to is the empty
+string, it's the current month. */
+func dateSuffixes(from, to string) ([]string, error) {
+	var (
+		y, m, _ = time.Now().Date()
+		start   = time.Date(y-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+		end     = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		err     error
+	)
+
+	/* Work out the range */
+	if "" != from {
+		if start, err = parseDateBound(from, false); nil != err {
+			return nil, fmt.Errorf("start %q: %v", from, err)
+		}
+	}
+	if "" != to {
+		if end, err = parseDateBound(to, true); nil != err {
+			return nil, fmt.Errorf("end %q: %v", to, err)
+		}
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf(
+			"end %v is before start %v",
+			end.Format("2006-01"),
+			start.Format("2006-01"),
+		)
+	}
+
+	/* Years, then months */
+	var ds []string
+	for y := start.Year(); y <= end.Year(); y++ {
+		ds = append(ds, fmt.Sprint(y))
+	}
+	for m := start; !m.After(end); m = m.AddDate(0, 1, 0) {
+		ds = append(ds, m.Format("200601"))
+	}
+	return ds, nil
+}
+
+/* parseDateBound parses s as YYYY-MM or YYYY.  A year on its own is December
+if isEnd is true and January otherwise. */
+func parseDateBound(s string, isEnd bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01", s); nil == err {
+		return t, nil
+	}
+	t, err := time.Parse("2006", s)
+	if nil != err {
+		return time.Time{}, fmt.Errorf("need YYYY or YYYY-MM")
+	}
+	if isEnd {
+		t = t.AddDate(0, 11, 0)
+	}
+	return t, nil
+}
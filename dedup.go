@@ -0,0 +1,96 @@
+package main
+
+/*
+ * dedup.go
+ * Don't report the same bucket over and over
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/* dedupWindow remembers found buckets for a while, so they're not checked and
+reported again until the window's passed.  A nil *dedupWindow remembers
+nothing.  It is safe to call dedupWindow's methods from multiple
+goroutines. */
+type dedupWindow struct {
+	window  time.Duration
+	skipped uint64
+
+	l         sync.Mutex
+	found     map[string]time.Time /* When each bucket was found */
+	lastSweep time.Time
+}
+
+/* newDedupWindow returns a dedupWindow which remembers buckets for d, or nil
+if d isn't positive. */
+func newDedupWindow(d time.Duration) *dedupWindow {
+	if 0 >= d {
+		return nil
+	}
+	return &dedupWindow{
+		window:    d,
+		found:     make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+/* Skip returns true, and counts it, if the bucket named n was found within
+the window. */
+func (d *dedupWindow) Skip(n string) bool {
+	if nil == d {
+		return false
+	}
+	d.l.Lock()
+	defer d.l.Unlock()
+	t, ok := d.found[n]
+	if !ok {
+		return false
+	}
+	if time.Since(t) >= d.window {
+		delete(d.found, n)
+		return false
+	}
+	atomic.AddUint64(&d.skipped, 1)
+	return true
+}
+
+/* Found notes that the bucket named n was found, unless it was already found
+within the window.  Buckets found longer ago than the window are forgotten
+every so often. */
+func (d *dedupWindow) Found(n string) {
+	if nil == d {
+		return
+	}
+	now := time.Now()
+	d.l.Lock()
+	defer d.l.Unlock()
+	if t, ok := d.found[n]; !ok || now.Sub(t) >= d.window {
+		d.found[n] = now
+	}
+
+	/* Forget old buckets, but not too often */
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for k, t := range d.found {
+		if now.Sub(t) >= d.window {
+			delete(d.found, k)
+		}
+	}
+}
+
+/* Skipped returns the number of checks skipped because the bucket was found
+within the window. */
+func (d *dedupWindow) Skipped() uint64 {
+	if nil == d {
+		return 0
+	}
+	return atomic.LoadUint64(&d.skipped)
+}
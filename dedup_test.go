@@ -0,0 +1,66 @@
+package main
+
+/*
+ * dedup_test.go
+ * Tests for not reporting the same bucket over and over
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+/* sliceSink is a resultSink which remembers what it's sent. */
+type sliceSink []Result
+
+/* Send appends r to s. */
+func (s *sliceSink) Send(r Result) { *s = append(*s, r) }
+
+func TestEmitDedup(t *testing.T) {
+	for _, c := range []struct {
+		status string
+		want   bool
+	}{
+		{StatusPublic, true},
+		{StatusForbidden, true},
+		{StatusNotBucket, false},
+		{StatusUnexpected, false},
+	} {
+		for _, withSink := range []bool{false, true} {
+			var sink sliceSink
+			conf := newTestCheckConfig(nil)
+			conf.dedup = newDedupWindow(time.Hour)
+			if withSink {
+				conf.sinks = []resultSink{&sink}
+			}
+			conf.emit(
+				candidate{name: "bucket", input: "bucket"},
+				"https://example.com/bucket",
+				c.status,
+				nil,
+				"",
+				0,
+				nil,
+			)
+			if got := conf.dedup.Skip("bucket"); c.want != got {
+				t.Errorf(
+					"%v (sink: %v): skipped %v, want %v",
+					c.status,
+					withSink,
+					got,
+					c.want,
+				)
+			}
+			if withSink && 1 != len(sink) {
+				t.Errorf(
+					"%v: sent %v results, want 1",
+					c.status,
+					len(sink),
+				)
+			}
+		}
+	}
+}
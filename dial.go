@@ -0,0 +1,85 @@
+package main
+
+/*
+ * dial.go
+ * Spread connections over local addresses
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DIALTIMEOUT is how long to wait for a connection to be made
+const DIALTIMEOUT = 30 * time.Second
+
+/* bindDialer makes connections from each of a list of local addresses in
+turn. */
+type bindDialer struct {
+	dialers []*net.Dialer
+	next    uint32
+}
+
+/* newBindDialer returns a bindDialer which uses the local IP addresses in the
+comma-separated list ips, or nil if ips is the empty string. */
+func newBindDialer(ips string) (*bindDialer, error) {
+	if "" == ips {
+		return nil, nil
+	}
+	d := &bindDialer{}
+	for _, s := range strings.Split(ips, ",") {
+		s = strings.TrimSpace(s)
+		if "" == s {
+			continue
+		}
+		ip := net.ParseIP(s)
+		if nil == ip {
+			return nil, fmt.Errorf("invalid IP address %q", s)
+		}
+		d.dialers = append(d.dialers, &net.Dialer{
+			LocalAddr: &net.TCPAddr{IP: ip},
+			Timeout:   DIALTIMEOUT,
+			KeepAlive: DIALTIMEOUT,
+		})
+	}
+	if 0 == len(d.dialers) {
+		return nil, fmt.Errorf("no addresses given")
+	}
+	return d, nil
+}
+
+/* DialContext connects to addr from the next local address.  The network is
+narrowed to the local address' family, so IPv4 addresses only make IPv4
+connections and likewise for IPv6. */
+func (d *bindDialer) DialContext(
+	ctx context.Context,
+	network string,
+	addr string,
+) (net.Conn, error) {
+	n := atomic.AddUint32(&d.next, 1) - 1
+	dl := d.dialers[int(n%uint32(len(d.dialers)))]
+	if "tcp" == network {
+		if nil != dl.LocalAddr.(*net.TCPAddr).IP.To4() {
+			network = "tcp4"
+		} else {
+			network = "tcp6"
+		}
+	}
+	return dl.DialContext(ctx, network, addr)
+}
+
+/* String returns the local addresses d uses. */
+func (d *bindDialer) String() string {
+	as := make([]string, len(d.dialers))
+	for i, dl := range d.dialers {
+		as[i] = dl.LocalAddr.(*net.TCPAddr).IP.String()
+	}
+	return strings.Join(as, ", ")
+}
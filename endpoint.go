@@ -0,0 +1,278 @@
+package main
+
+/*
+ * endpoint.go
+ * Work out which URL to use for a region
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// REGIONURL is the default regional S3 URL, with a placeholder for
+	// the region
+	REGIONURL = "https://s3.%v.amazonaws.com"
+
+	// DUALSTACKURL is the global S3 dualstack URL
+	DUALSTACKURL = "https://s3.dualstack.us-east-1.amazonaws.com"
+
+	// DUALSTACKREGIONURL is the regional S3 dualstack URL, with a
+	// placeholder for the region
+	DUALSTACKREGIONURL = "https://s3.dualstack.%v.amazonaws.com"
+
+	// ACCELERATEURL is the S3 Transfer Acceleration URL, with a
+	// placeholder for the bucket name
+	ACCELERATEURL = "https://%v.s3-accelerate.amazonaws.com"
+
+	// VIRTUALURL is the global virtual-hosted-style S3 URL, with a
+	// placeholder for the bucket name
+	VIRTUALURL = "https://%v.s3.amazonaws.com"
+
+	// VIRTUALREGIONURL is the regional virtual-hosted-style S3 URL, with
+	// placeholders for the bucket name and region
+	VIRTUALREGIONURL = "https://%v.s3.%v.amazonaws.com"
+
+	// ACCESSPOINTURL is the S3 Access Point URL, with placeholders for
+	// the access point name, account ID, and region
+	ACCESSPOINTURL = "https://%v-%v.s3-accesspoint.%v.amazonaws.com"
+
+	// OBJECTLAMBDAURL is the S3 Object Lambda Access Point URL, with
+	// placeholders for the access point name, account ID, and region
+	OBJECTLAMBDAURL = "https://%v-%v.s3-object-lambda.%v.amazonaws.com"
+
+	// ACCESSPOINTREGION is the region in which Access Points are checked
+	// when the region isn't known
+	ACCESSPOINTREGION = "us-east-1"
+
+	// ENDPOINTREGION is the region used with an S3-compatible endpoint
+	// whose URL depends on the region before a bucket's region is known
+	ENDPOINTREGION = "us-east-1"
+)
+
+// PARTITIONS maps the region prefixes of AWS partitions other than the
+// standard one to their regional S3 URLs.
+var PARTITIONS = []struct {
+	Prefix string
+	URL    string
+}{
+	{"cn-", "https://s3.%v.amazonaws.com.cn"},  /* aws-cn */
+	{"us-gov-", "https://s3.%v.amazonaws.com"}, /* aws-us-gov */
+}
+
+/* regionalHostRE finds the region in the host of a regional S3 URL, path- or
+virtual-hosted-style, such as s3.eu-west-1.amazonaws.com or
+bucket.s3-eu-west-1.amazonaws.com. */
+var regionalHostRE = regexp.MustCompile(
+	`(?:^|\.)s3[.-](?:dualstack\.)?([a-z]{2}(?:-[a-z]+)+-[0-9]+)` +
+		`\.amazonaws\.com(?:\.cn)?$`,
+)
+
+/* regionFromLocation returns the region of the regional S3 URL loc, such as
+a redirect's Location header, or the empty string if it hasn't got one. */
+func regionFromLocation(loc string) string {
+	u, err := url.Parse(loc)
+	if nil != err {
+		return ""
+	}
+	m := regionalHostRE.FindStringSubmatch(strings.ToLower(u.Hostname()))
+	if nil == m {
+		return ""
+	}
+	return m[1]
+}
+
+/* endpoints works out which URL to use for a region. */
+type endpoints struct {
+	/* name identifies the endpoints in statistics */
+	name string
+
+	/* global is the URL to use when the region isn't known */
+	global string
+
+	/* regional is the URL to use for a region, with a placeholder for
+	the region.  If it's REGIONURL, regions in other partitions use the
+	partition's URL instead.  If it's the empty string, global is used
+	for every region. */
+	regional string
+
+	/* virtual indicates the bucket name is part of the URL's host,
+	in place of global's and regional's first placeholder, rather than
+	being sent in the Host header.  Either way, TLS's SNI is the URL's
+	host, so path-style requests for dotted names are fine, but
+	virtual-hosted-style requests for them fail certificate
+	verification. */
+	virtual bool
+
+	/* path indicates the bucket name is the first part of the URL's
+	path rather than being sent in the Host header, which only S3 itself
+	understands.  It's ignored if virtual is set. */
+	path bool
+
+	/* statuses maps the HTTP status codes sent by a provider other than
+	S3 to the ones S3 sends for the same thing.  Codes not in statuses
+	mean what they mean to S3. */
+	statuses map[int]int
+
+	/* notS3 indicates the endpoints are for something which only works
+	like S3, so S3's own endpoints won't help */
+	notS3 bool
+
+	/* start, if not the empty string, is the region in which checks
+	start, rather than at global.  Buckets in partitions other than the
+	standard one are only found this way, as global doesn't know about
+	them. */
+	start string
+}
+
+// DUALSTACKENDPOINTS are the S3 dualstack (IPv4 and IPv6) endpoints
+var DUALSTACKENDPOINTS = endpoints{
+	name:     "dualstack",
+	global:   DUALSTACKURL,
+	regional: DUALSTACKREGIONURL,
+}
+
+// VIRTUALENDPOINTS are the virtual-hosted-style S3 endpoints, which
+// some buckets require
+var VIRTUALENDPOINTS = endpoints{
+	name:     "virtual-hosted",
+	global:   VIRTUALURL,
+	regional: VIRTUALREGIONURL,
+	virtual:  true,
+}
+
+// ACCELERATEENDPOINTS are the S3 Transfer Acceleration endpoints, which
+// work for buckets in any region
+var ACCELERATEENDPOINTS = endpoints{
+	name:    "accelerate",
+	global:  ACCELERATEURL,
+	virtual: true,
+}
+
+/* newEndpoints returns an endpoints using the regional URL template t, which
+must have a single %v for the region. */
+func newEndpoints(t string) (endpoints, error) {
+	if 1 != strings.Count(t, "%v") {
+		return endpoints{}, fmt.Errorf(
+			"need exactly one %%v in regional URL template %q",
+			t,
+		)
+	}
+	return endpoints{name: "standard", global: S3URL, regional: t}, nil
+}
+
+/* newCompatibleEndpoints returns an endpoints for an S3-compatible service
+using the URL template t, which may have a %v for the region.  If it does,
+defRegion is used when the region isn't known.  If it doesn't, every region's
+buckets are checked at t.  Requests are path-style, with the bucket name
+after t's path. */
+func newCompatibleEndpoints(t, defRegion string) (endpoints, error) {
+	ep := endpoints{
+		name:   "endpoint",
+		global: t,
+		path:   true,
+		notS3:  true,
+	}
+	switch strings.Count(t, "%v") {
+	case 0:
+	case 1:
+		if "" == defRegion {
+			return endpoints{}, fmt.Errorf(
+				"need a default region for URL template %q",
+				t,
+			)
+		}
+		ep.global = fmt.Sprintf(t, defRegion)
+		ep.regional = t
+	default:
+		return endpoints{}, fmt.Errorf(
+			"too many %%v in URL template %q",
+			t,
+		)
+	}
+	if _, err := url.Parse(ep.global); nil != err {
+		return endpoints{}, err
+	}
+	return ep, nil
+}
+
+/* newAccessPointEndpoints returns the endpoints for the S3 Access Points and
+S3 Object Lambda Access Points of the AWS account with the given ID.  Names
+are checked as access point names rather than bucket names. */
+func newAccessPointEndpoints(account string) ([]endpoints, error) {
+	if 12 != len(account) || "" != strings.Trim(account, "0123456789") {
+		return nil, fmt.Errorf(
+			"account ID %q isn't 12 digits",
+			account,
+		)
+	}
+	var eps []endpoints
+	for _, ap := range []struct {
+		name string
+		url  string
+	}{
+		{"access-point", ACCESSPOINTURL},
+		{"object-lambda", OBJECTLAMBDAURL},
+	} {
+		/* Bake in the account, leaving the name and region */
+		t := strings.Replace(ap.url, "%v-%v", "%v-"+account, 1)
+		eps = append(eps, endpoints{
+			name:     ap.name,
+			global:   fmt.Sprintf(t, "%v", ACCESSPOINTREGION),
+			regional: t,
+			virtual:  true,
+		})
+	}
+	return eps, nil
+}
+
+/* status returns the HTTP status code S3 would send in place of code. */
+func (e endpoints) status(code int) int {
+	if s, ok := e.statuses[code]; ok {
+		return s
+	}
+	return code
+}
+
+/* inHost returns true if the bucket name is sent in the Host header. */
+func (e endpoints) inHost() bool { return !e.virtual && !e.path }
+
+/* url returns the URL to use to check the bucket in the given region, which
+may be the empty string if the region's not known. */
+func (e endpoints) url(bucket, region string) string {
+	u := e.hostURL(bucket, region)
+	if !e.virtual && e.path {
+		u = strings.TrimSuffix(u, "/") + "/" + bucket
+	}
+	return u
+}
+
+/* hostURL returns the URL of the host to ask about the bucket in the given
+region, without the bucket name for path-style endpoints. */
+func (e endpoints) hostURL(bucket, region string) string {
+	if "" == region || "" == e.regional {
+		if e.virtual {
+			return fmt.Sprintf(e.global, bucket)
+		}
+		return e.global
+	}
+	t := e.regional
+	if REGIONURL == t {
+		for _, p := range PARTITIONS {
+			if strings.HasPrefix(region, p.Prefix) {
+				t = p.URL
+				break
+			}
+		}
+	}
+	if e.virtual {
+		return fmt.Sprintf(t, bucket, region)
+	}
+	return fmt.Sprintf(t, region)
+}
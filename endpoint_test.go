@@ -0,0 +1,145 @@
+package main
+
+/*
+ * endpoint_test.go
+ * Tests for working out which URL to use for a region
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestNewCompatibleEndpointsPathStyle(t *testing.T) {
+	var (
+		l   sync.Mutex
+		got []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		l.Lock()
+		defer l.Unlock()
+		got = append(got, r.Method+" "+r.Host+r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if nil != err {
+		t.Fatalf("Parsing server URL %q: %v", srv.URL, err)
+	}
+
+	ep, err := newCompatibleEndpoints(srv.URL, "")
+	if nil != err {
+		t.Fatalf("newCompatibleEndpoints: %v", err)
+	}
+	for _, head := range []bool{false, true} {
+		got = nil
+		conf := newTestCheckConfig(srv.Client())
+		conf.headRegion = head
+		check(
+			context.Background(),
+			candidate{name: "bucket", input: "bucket"},
+			"",
+			ep,
+			MAXRECURSION,
+			0,
+			conf,
+		)
+		want := "GET " + u.Host + "/bucket"
+		if head {
+			want = "HEAD " + u.Host + "/bucket"
+		}
+		if 1 != len(got) || want != got[0] {
+			t.Errorf(
+				"With -head-region %v: got requests %q, "+
+					"want [%q]",
+				head,
+				got,
+				want,
+			)
+		}
+	}
+}
+
+func TestEndpointsURLPartitions(t *testing.T) {
+	std, err := newEndpoints(REGIONURL)
+	if nil != err {
+		t.Fatalf("newEndpoints(REGIONURL): %v", err)
+	}
+	legacy, err := newEndpoints("https://s3-%v.amazonaws.com")
+	if nil != err {
+		t.Fatalf("newEndpoints(legacy): %v", err)
+	}
+	for _, c := range []struct {
+		ep     endpoints
+		region string
+		want   string
+	}{
+		{std, "", S3URL},
+		{std, "eu-west-1", "https://s3.eu-west-1.amazonaws.com"},
+		{
+			std,
+			"cn-north-1",
+			"https://s3.cn-north-1.amazonaws.com.cn",
+		},
+		{
+			std,
+			"cn-northwest-1",
+			"https://s3.cn-northwest-1.amazonaws.com.cn",
+		},
+		{
+			std,
+			"us-gov-west-1",
+			"https://s3.us-gov-west-1.amazonaws.com",
+		},
+		{
+			std,
+			"us-gov-east-1",
+			"https://s3.us-gov-east-1.amazonaws.com",
+		},
+		/* Other templates are used as-is */
+		{legacy, "eu-west-1", "https://s3-eu-west-1.amazonaws.com"},
+		{legacy, "cn-north-1", "https://s3-cn-north-1.amazonaws.com"},
+	} {
+		if got := c.ep.url("bucket", c.region); c.want != got {
+			t.Errorf(
+				"%v in %q: got %q, want %q",
+				c.ep.regional,
+				c.region,
+				got,
+				c.want,
+			)
+		}
+	}
+	if _, err := newEndpoints(S3URL); nil == err {
+		t.Errorf("Template without %%v didn't return an error")
+	}
+}
+
+func TestRegionFromLocation(t *testing.T) {
+	for _, c := range []struct {
+		loc  string
+		want string
+	}{
+		{"https://s3.cn-north-1.amazonaws.com.cn/b", "cn-north-1"},
+		{"https://b.s3.cn-north-1.amazonaws.com.cn/", "cn-north-1"},
+		{"https://s3.us-gov-west-1.amazonaws.com/b", "us-gov-west-1"},
+		{"https://b.s3-us-gov-west-1.amazonaws.com/", "us-gov-west-1"},
+		{"https://s3.dualstack.eu-west-1.amazonaws.com/", "eu-west-1"},
+		{"https://s3.amazonaws.com/b", ""},
+		{"https://example.com/", ""},
+	} {
+		if got := regionFromLocation(c.loc); c.want != got {
+			t.Errorf("%q: got %q, want %q", c.loc, got, c.want)
+		}
+	}
+}
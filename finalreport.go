@@ -0,0 +1,96 @@
+package main
+
+/*
+ * finalreport.go
+ * Deduplicated report of findings, written at the end of a run
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+)
+
+/* finalReport is a resultSink which keeps the findings it's sent, one per
+bucket name and status, until they're written sorted at the end of the run.
+Names which aren't buckets aren't kept.  A nil *finalReport keeps nothing.  It
+is safe to call finalReport's methods from multiple goroutines. */
+type finalReport struct {
+	l  sync.Mutex
+	rs map[[2]string]Result
+}
+
+/* newFinalReport returns a new finalReport, or nil if enabled is false. */
+func newFinalReport(enabled bool) *finalReport {
+	if !enabled {
+		return nil
+	}
+	return &finalReport{rs: make(map[[2]string]Result)}
+}
+
+/* Send keeps r, unless it's not a bucket or its bucket's already been
+reported with the same status. */
+func (f *finalReport) Send(r Result) {
+	if nil == f || StatusNotBucket == r.Status {
+		return
+	}
+	k := [2]string{r.Name, r.Status}
+	f.l.Lock()
+	defer f.l.Unlock()
+	if _, ok := f.rs[k]; ok {
+		return
+	}
+	f.rs[k] = r
+}
+
+/* Results returns the findings, sorted by name and then status. */
+func (f *finalReport) Results() []Result {
+	if nil == f {
+		return nil
+	}
+	f.l.Lock()
+	rs := make([]Result, 0, len(f.rs))
+	for _, r := range f.rs {
+		rs = append(rs, r)
+	}
+	f.l.Unlock()
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Name != rs[j].Name {
+			return rs[i].Name < rs[j].Name
+		}
+		return rs[i].Status < rs[j].Status
+	})
+	return rs
+}
+
+/* Write writes the findings to the file named fn as JSON lines, in the order
+Results returns them, and returns the number written. */
+func (f *finalReport) Write(fn string) (int, error) {
+	if nil == f {
+		return 0, nil
+	}
+	rs := f.Results()
+
+	/* Write it out */
+	o, err := os.Create(fn)
+	if nil != err {
+		return 0, err
+	}
+	defer o.Close()
+	w := bufio.NewWriter(o)
+	enc := json.NewEncoder(w)
+	for _, r := range rs {
+		if err := enc.Encode(r); nil != err {
+			return 0, err
+		}
+	}
+	if err := w.Flush(); nil != err {
+		return 0, err
+	}
+	return len(rs), o.Close()
+}
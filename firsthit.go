@@ -0,0 +1,85 @@
+package main
+
+/*
+ * firsthit.go
+ * Stop checking an input's names once one's found
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+/* firstHits keeps track of which input names have had a public bucket found,
+so the rest of the names generated from them needn't be checked.  Inputs are
+kept in an LRU cache, so very old inputs may be forgotten.  A nil *firstHits
+never has a hit.  It is safe to call firstHits' methods from multiple
+goroutines. */
+type firstHits struct {
+	c *lru.Cache
+}
+
+/* firstHit is the state of a single input name. */
+type firstHit struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+/* newFirstHits returns a firstHits which remembers up to size inputs, or nil
+if enabled is false. */
+func newFirstHits(enabled bool, size int) (*firstHits, error) {
+	if !enabled {
+		return nil, nil
+	}
+	c, err := lru.New(size)
+	if nil != err {
+		return nil, err
+	}
+	return &firstHits{c: c}, nil
+}
+
+/* get returns the state for input, adding it if it's not there. */
+func (f *firstHits) get(input string) *firstHit {
+	if v, ok := f.c.Get(input); ok {
+		return v.(*firstHit)
+	}
+	h := &firstHit{}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	/* Someone else may have beaten us to it */
+	if v, ok, _ := f.c.PeekOrAdd(input, h); ok {
+		h.cancel()
+		return v.(*firstHit)
+	}
+	return h
+}
+
+/* Context returns a context which is cancelled when a public bucket is found
+for input. */
+func (f *firstHits) Context(input string) context.Context {
+	if nil == f {
+		return context.Background()
+	}
+	return f.get(input).ctx
+}
+
+/* Hit returns true if a public bucket has been found for input. */
+func (f *firstHits) Hit(input string) bool {
+	if nil == f {
+		return false
+	}
+	v, ok := f.c.Peek(input)
+	return ok && nil != v.(*firstHit).ctx.Err()
+}
+
+/* Found notes that a public bucket has been found for input, and cancels its
+in-flight requests. */
+func (f *firstHits) Found(input string) {
+	if nil == f {
+		return
+	}
+	f.get(input).cancel()
+}
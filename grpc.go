@@ -0,0 +1,243 @@
+package main
+
+/*
+ * grpc.go
+ * Check names streamed over gRPC
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative s3finder.proto
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCBUFLEN is the number of results to buffer for each gRPC stream
+const GRPCBUFLEN = 1024
+
+/* grpcServer checks names streamed to it by gRPC clients, with the same
+providers and settings as any other check.  Every stream's names share a pool
+of workers and a rate limit. */
+type grpcServer struct {
+	UnimplementedFinderServer
+
+	srv     *grpc.Server
+	conf    *checkConfig
+	rules   namingRules
+	limit   *rateLimiter
+	workers chan uint
+}
+
+/* newGRPCServer listens for gRPC clients on addr.  Names clients send are
+checked according to conf by at most nWorkers workers at once, numbered from
+firstWorker, at no more than rate names per second.  If addr is the empty
+string, newGRPCServer returns nil. */
+func newGRPCServer(
+	addr string,
+	conf *checkConfig,
+	rules namingRules,
+	nWorkers uint,
+	firstWorker uint,
+	rate float64,
+) (*grpcServer, error) {
+	if "" == addr {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if nil != err {
+		return nil, err
+	}
+	s := &grpcServer{
+		srv:     grpc.NewServer(),
+		conf:    conf,
+		rules:   rules,
+		limit:   newRateLimiter(rate),
+		workers: make(chan uint, nWorkers),
+	}
+	for i := uint(0); i < nWorkers; i++ {
+		s.workers <- firstWorker + i
+	}
+	RegisterFinderServer(s.srv, s)
+	go func() {
+		if err := s.srv.Serve(ln); nil != err {
+			elog.Printf("Error serving gRPC clients: %v", err)
+		}
+	}()
+	log.Printf("Listening for gRPC clients on %v", ln.Addr())
+	return s, nil
+}
+
+/* CheckNames checks the names sent on stream and sends back the results.  It
+returns once the client's finished sending names and every name's been
+checked. */
+func (s *grpcServer) CheckNames(stream Finder_CheckNamesServer) error {
+	var (
+		ctx   = stream.Context()
+		resch = make(chan Result, GRPCBUFLEN)
+		errch = make(chan error, 1)
+		conf  = *s.conf
+		wg    sync.WaitGroup
+	)
+
+	/* This stream's results go back to it, as well as wherever else
+	results go. */
+	conf.sinks = append(
+		append([]resultSink(nil), s.conf.sinks...),
+		grpcSink{ctx: ctx, ch: resch},
+	)
+
+	/* Send results back as they come.  If the client goes away, the rest
+	are discarded so checks don't block. */
+	go func() {
+		for r := range resch {
+			if err := stream.Send(resultMessage(r)); nil != err {
+				errch <- err
+				for range resch {
+				}
+				return
+			}
+		}
+		errch <- nil
+	}()
+
+	/* Check names until the client's done */
+	var err error
+	for nil == err {
+		var req *CheckNamesRequest
+		if req, err = stream.Recv(); nil != err {
+			break
+		}
+		name := strings.TrimSpace(req.GetName())
+		if p := s.rules.problem(name); "" != p {
+			log.Printf(
+				"[%v] Invalid name from gRPC client: %v",
+				name,
+				p,
+			)
+			continue
+		}
+
+		/* Wait for our turn */
+		s.limit.Wait()
+		var worker uint
+		select {
+		case worker = <-s.workers:
+		case <-ctx.Done():
+			err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { s.workers <- worker }()
+			s.check(ctx, name, worker, &conf)
+		}()
+	}
+
+	/* Wait for the last checks and their results */
+	wg.Wait()
+	close(resch)
+	serr := <-errch
+	if io.EOF == err {
+		return serr
+	}
+	return err
+}
+
+/* check checks name with each of conf's providers, using the worker number
+worker. */
+func (s *grpcServer) check(
+	ctx context.Context,
+	name string,
+	worker uint,
+	conf *checkConfig,
+) {
+	if _, ok := conf.known[name]; ok {
+		return
+	}
+	cand := candidate{
+		name:   name,
+		source: SourceLiteral,
+		input:  name,
+	}
+	/* Unlike checker, checks aren't coalesced, as the client would
+	miss the other check's results. */
+	conf.pause.Wait()
+	ctx, span := startCandidateSpan(ctx, cand)
+	defer span.End()
+	for _, p := range conf.providers {
+		if nil != ctx.Err() {
+			return
+		}
+		p.Check(ctx, cand, worker, conf)
+	}
+}
+
+/* Close disconnects clients and stops listening.  It is a no-op on a nil
+*grpcServer. */
+func (s *grpcServer) Close() {
+	if nil == s {
+		return
+	}
+	s.srv.Stop()
+}
+
+/* grpcSink is a resultSink which queues results for a single gRPC stream.
+Sending blocks only the stream's own checks, and only until the stream's
+context is done. */
+type grpcSink struct {
+	ctx context.Context
+	ch  chan<- Result
+}
+
+/* Send queues r to be sent to the stream. */
+func (s grpcSink) Send(r Result) {
+	select {
+	case s.ch <- r:
+	case <-s.ctx.Done():
+	}
+}
+
+/* resultMessage converts r to a CheckResult. */
+func resultMessage(r Result) *CheckResult {
+	m := &CheckResult{
+		Name:             r.Name,
+		BucketUrl:        r.BucketURL,
+		Status:           r.Status,
+		Severity:         r.Severity,
+		HttpStatus:       int32(r.HTTPStatus),
+		Region:           r.Region,
+		Timestamp:        timestamppb.New(r.Time),
+		LatencyMs:        r.LatencyMS,
+		Source:           r.Source,
+		Versioning:       r.Versioning,
+		Headers:          r.Headers,
+		StatusLine:       r.StatusLine,
+		Body:             r.Body,
+		Documents:        r.Documents,
+		RedirectUrl:      r.RedirectURL,
+		RedirectHost:     r.RedirectHost,
+		ReadablePrefixes: r.Prefixes,
+		OwnerId:          r.OwnerID,
+		OwnerName:        r.OwnerName,
+	}
+	if nil != r.LifecycleRules {
+		n := int32(*r.LifecycleRules)
+		m.LifecycleRules = &n
+	}
+	if nil != r.RunStart {
+		m.RunId = r.RunID
+		m.RunStart = timestamppb.New(*r.RunStart)
+	}
+	return m
+}
@@ -0,0 +1,47 @@
+package main
+
+/*
+ * grpc_test.go
+ * Tests for the gRPC interface
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultMessage(t *testing.T) {
+	m := resultMessage(Result{
+		Name:     "bucket",
+		Status:   StatusPublic,
+		Severity: SeverityMedium,
+	})
+	if "bucket" != m.Name ||
+		StatusPublic != m.Status ||
+		SeverityMedium != m.Severity {
+		t.Errorf("Got message %v", m)
+	}
+	if "" != m.RunId || nil != m.RunStart {
+		t.Errorf(
+			"Got run %q started %v without -run-id",
+			m.RunId,
+			m.RunStart,
+		)
+	}
+
+	/* Runs with IDs */
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	m = resultMessage(Result{RunID: "id", RunStart: &start})
+	if "id" != m.RunId || !start.Equal(m.RunStart.AsTime()) {
+		t.Errorf(
+			"Got run %q started %v, want %q started %v",
+			m.RunId,
+			m.RunStart.AsTime(),
+			"id",
+			start,
+		)
+	}
+}
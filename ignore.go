@@ -0,0 +1,78 @@
+package main
+
+/*
+ * ignore.go
+ * Suppress output nobody wants
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Output classifications which may be suppressed with -ignore
+const (
+	IgnoreForbidden  = "forbidden"
+	IgnoreNotBucket  = "not-bucket"
+	IgnoreError      = "error"
+	IgnoreBadRequest = "bad-request"
+	IgnoreExhausted  = "exhausted"
+)
+
+/* ignoreSet suppresses output with the classifications it holds, counting
+what it suppresses.  A nil *ignoreSet suppresses nothing.  It is safe to call
+ignoreSet's methods from multiple goroutines. */
+type ignoreSet struct {
+	classes    map[string]struct{}
+	suppressed *requestCounts
+}
+
+/* newIgnoreSet returns an ignoreSet which suppresses the comma-separated
+classifications in s.  If s is the empty string, newIgnoreSet returns nil. */
+func newIgnoreSet(s string) (*ignoreSet, error) {
+	is := &ignoreSet{
+		classes:    make(map[string]struct{}),
+		suppressed: newRequestCounts(),
+	}
+	for _, c := range strings.Split(s, ",") {
+		switch c = strings.TrimSpace(c); c {
+		case "":
+		case IgnoreForbidden,
+			IgnoreNotBucket,
+			IgnoreError,
+			IgnoreBadRequest,
+			IgnoreExhausted:
+			is.classes[c] = struct{}{}
+		default:
+			return nil, fmt.Errorf("unknown classification %q", c)
+		}
+	}
+	if 0 == len(is.classes) {
+		return nil, nil
+	}
+	return is, nil
+}
+
+/* Suppress returns true, and counts it, if output with the classification c
+should be suppressed. */
+func (s *ignoreSet) Suppress(c string) bool {
+	if nil == s {
+		return false
+	}
+	if _, ok := s.classes[c]; !ok {
+		return false
+	}
+	s.suppressed.Add(c)
+	return true
+}
+
+/* String returns the amount of output suppressed, by classification. */
+func (s *ignoreSet) String() string {
+	if nil == s {
+		return "none"
+	}
+	return s.suppressed.String()
+}
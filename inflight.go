@@ -0,0 +1,69 @@
+package main
+
+/*
+ * inflight.go
+ * Coalesce identical concurrent checks
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/* inFlight makes sure only one of a set of identical concurrent checks is
+actually made, in the manner of golang.org/x/sync/singleflight.  The others
+wait for it to finish and share its result, which will already have been
+reported.  A nil *inFlight coalesces nothing.  It is safe to call inFlight's
+methods from multiple goroutines. */
+type inFlight struct {
+	l sync.Mutex
+	m map[string]chan struct{}
+	n uint64 /* Coalesced calls */
+}
+
+/* newInFlight returns a new inFlight. */
+func newInFlight() *inFlight {
+	return &inFlight{m: make(map[string]chan struct{})}
+}
+
+/* Do calls f, unless a call with the same key is in progress, in which case
+Do waits for that call to finish instead.  Do returns true if it called f. */
+func (i *inFlight) Do(key string, f func()) bool {
+	if nil == i {
+		f()
+		return true
+	}
+
+	/* If someone else is already on it, wait for them */
+	i.l.Lock()
+	if ch, ok := i.m[key]; ok {
+		i.l.Unlock()
+		atomic.AddUint64(&i.n, 1)
+		<-ch
+		return false
+	}
+	ch := make(chan struct{})
+	i.m[key] = ch
+	i.l.Unlock()
+
+	/* Our turn */
+	defer func() {
+		i.l.Lock()
+		delete(i.m, key)
+		i.l.Unlock()
+		close(ch)
+	}()
+	f()
+	return true
+}
+
+/* Coalesced returns the number of calls to Do which didn't call f. */
+func (i *inFlight) Coalesced() uint64 {
+	if nil == i {
+		return 0
+	}
+	return atomic.LoadUint64(&i.n)
+}
@@ -0,0 +1,154 @@
+package main
+
+/*
+ * inputs.go
+ * Places names to check come from
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+/* inputSource is somewhere names to check come from, e.g. the command line,
+a file, or the certificate stream. */
+type inputSource interface {
+	/* Names returns a channel on which the source's names are sent.  The
+	channel is closed when there are no more names or ctx is done. */
+	Names(ctx context.Context) <-chan string
+
+	/* String describes the source, for logging */
+	String() string
+}
+
+/* listSource is an inputSource with a fixed list of names. */
+type listSource struct {
+	desc  string
+	names []string
+}
+
+/* Names sends s's names. */
+func (s listSource) Names(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, n := range s.names {
+			select {
+			case ch <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+/* String returns s's description. */
+func (s listSource) String() string { return s.desc }
+
+/* funcSource is an inputSource which gets its names from a function, which
+should send names to c until it runs out or ctx is done.  Errors are logged. */
+type funcSource struct {
+	desc string
+	f    func(ctx context.Context, c chan<- string) error
+}
+
+/* Names calls s's function and sends the names it sends. */
+func (s funcSource) Names(ctx context.Context) <-chan string {
+	var (
+		ch    = make(chan string)
+		inner = make(chan string)
+	)
+
+	/* Get the names */
+	go func() {
+		defer close(inner)
+		if err := s.f(ctx, inner); nil != err {
+			elog.Printf("Error reading names from %v: %v", s, err)
+			return
+		}
+		log.Printf("Finished reading names from %v", s)
+	}()
+
+	/* Send them on, until we're told to stop.  The function may not
+	notice we've stopped, e.g. if it's waiting on the next line of stdin,
+	so we don't wait for it to finish.  Any names it still sends are
+	discarded, so it doesn't block. */
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case n, ok := <-inner:
+				if !ok {
+					return
+				}
+				select {
+				case ch <- n:
+				case <-ctx.Done():
+					go discardNames(inner)
+					return
+				}
+			case <-ctx.Done():
+				go discardNames(inner)
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+/* String returns s's description. */
+func (s funcSource) String() string { return s.desc }
+
+/* discardNames reads and discards names from c until it's closed. */
+func discardNames(c <-chan string) {
+	for range c {
+	}
+}
+
+/* argNames returns the names on the command line, with URLs reduced to their
+hostnames. */
+func argNames() []string {
+	ns := make([]string, flag.NArg())
+	for i, a := range flag.Args() {
+		ns[i] = nameFromURL(a)
+	}
+	return ns
+}
+
+/* nameFromURL returns the hostname of s, without the scheme, credentials,
+port, or path, if s is a URL, or s itself if it isn't. */
+func nameFromURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if nil != err || "" == u.Hostname() {
+		return s
+	}
+	return u.Hostname()
+}
+
+/* mergeNames sends the names from all of srcs to out.  It returns when all of
+the sources are done. */
+func mergeNames(ctx context.Context, out chan<- string, srcs []inputSource) {
+	var wg sync.WaitGroup
+	for _, src := range srcs {
+		wg.Add(1)
+		go func(src inputSource) {
+			defer wg.Done()
+			for n := range src.Names(ctx) {
+				out <- n
+			}
+		}(src)
+	}
+	wg.Wait()
+}
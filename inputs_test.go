@@ -0,0 +1,51 @@
+package main
+
+/*
+ * inputs_test.go
+ * Tests for places names to check come from
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFuncSourceNamesStopsWithoutProducer(t *testing.T) {
+	var (
+		block = make(chan struct{})
+		sent  = make(chan struct{})
+	)
+	defer close(block)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	/* A function which, like reading stdin, ignores ctx */
+	ch := funcSource{desc: "test", f: func(
+		_ context.Context,
+		c chan<- string,
+	) error {
+		c <- "first"
+		close(sent)
+		<-block
+		c <- "second"
+		return nil
+	}}.Names(ctx)
+
+	if n := <-ch; "first" != n {
+		t.Fatalf("Got name %q, want %q", n, "first")
+	}
+	<-sent
+	cancel()
+	select {
+	case n, ok := <-ch:
+		if ok {
+			t.Fatalf("Got name %q after cancel", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Names not closed after cancel")
+	}
+}
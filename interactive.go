@@ -0,0 +1,154 @@
+package main
+
+/*
+ * interactive.go
+ * Let the user look at found buckets as they're found
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// INTERACTIVEPROMPT is printed when waiting for the user to pick a bucket
+const INTERACTIVEPROMPT = "Bucket to list> "
+
+/* interactive lists public buckets as they're found on a terminal and lets
+the user pick one to have its contents listed.  Other results are ignored. */
+type interactive struct {
+	l      sync.Mutex
+	out    io.Writer
+	client *http.Client
+	lim    listLimits
+	found  []Result
+}
+
+/* newInteractive returns an interactive which writes to the terminal out and
+uses client to list buckets, within lim. */
+func newInteractive(
+	out io.Writer,
+	client *http.Client,
+	lim listLimits,
+) *interactive {
+	return &interactive{out: out, client: client, lim: lim}
+}
+
+/* Send adds r to the list of buckets, if it's public. */
+func (i *interactive) Send(r Result) {
+	if StatusPublic != r.Status {
+		return
+	}
+	i.l.Lock()
+	defer i.l.Unlock()
+	i.found = append(i.found, r)
+	/* Don't leave the prompt in the middle of the line */
+	fmt.Fprintf(
+		i.out,
+		"\r\x1b[K%3d) %v\n%v",
+		len(i.found),
+		r.BucketURL,
+		INTERACTIVEPROMPT,
+	)
+}
+
+/* Run reads bucket numbers from in and lists the buckets, until in is
+closed.  An empty line lists the found buckets again. */
+func (i *interactive) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	i.prompt()
+	for scanner.Scan() {
+		t := strings.TrimSpace(scanner.Text())
+		if "" == t {
+			i.printFound()
+			i.prompt()
+			continue
+		}
+		n, err := strconv.Atoi(t)
+		if nil != err {
+			i.printf("Please enter a bucket number\n")
+			i.prompt()
+			continue
+		}
+		r, ok := i.get(n)
+		if !ok {
+			i.printf("No bucket %v\n", n)
+			i.prompt()
+			continue
+		}
+		i.list(r)
+		i.prompt()
+	}
+	return scanner.Err()
+}
+
+/* get returns the nth found bucket, counting from 1. */
+func (i *interactive) get(n int) (Result, bool) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	if n < 1 || len(i.found) < n {
+		return Result{}, false
+	}
+	return i.found[n-1], true
+}
+
+/* list prints r's listing. */
+func (i *interactive) list(r Result) {
+	n, truncated, err := listBucket(
+		i.client,
+		r.BucketURL,
+		i.lim,
+		func(o listedObject) {
+			i.printf(
+				"%12v %v %v\n",
+				o.Size,
+				o.LastModified,
+				o.Key,
+			)
+		},
+	)
+	if nil != err {
+		i.printf("Unable to list %v: %v\n", r.BucketURL, err)
+		return
+	}
+	switch {
+	case truncated:
+		i.printf("(truncated)\nFirst %v objects in %v\n", n, r.Name)
+	case 1 == n:
+		i.printf("1 object in %v\n", r.Name)
+	default:
+		i.printf("%v objects in %v\n", n, r.Name)
+	}
+}
+
+/* printFound prints the buckets found so far. */
+func (i *interactive) printFound() {
+	i.l.Lock()
+	defer i.l.Unlock()
+	if 0 == len(i.found) {
+		fmt.Fprintf(i.out, "No public buckets found yet\n")
+		return
+	}
+	for n, r := range i.found {
+		fmt.Fprintf(i.out, "%3d) %v\n", n+1, r.BucketURL)
+	}
+}
+
+/* prompt prints the prompt. */
+func (i *interactive) prompt() {
+	i.printf("%v", INTERACTIVEPROMPT)
+}
+
+/* printf prints to the terminal, without getting mixed up with Send. */
+func (i *interactive) printf(f string, a ...interface{}) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	fmt.Fprintf(i.out, f, a...)
+}
@@ -0,0 +1,75 @@
+package main
+
+/*
+ * interrupt.go
+ * Stop gracefully on the first SIGINT
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+/* interrupter finishes a run early on the first SIGINT, and exits on the
+second.  A nil *interrupter is never interrupted.  It is safe to call
+interrupter's methods from multiple goroutines. */
+type interrupter struct {
+	sigch  chan os.Signal
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+/* newInterrupter returns an interrupter which is watching for SIGINTs. */
+func newInterrupter() *interrupter {
+	i := &interrupter{sigch: make(chan os.Signal, 1)}
+	i.ctx, i.cancel = context.WithCancel(context.Background())
+	signal.Notify(i.sigch, os.Interrupt)
+	go i.watch()
+	return i
+}
+
+/* watch waits for SIGINTs. */
+func (i *interrupter) watch() {
+	if _, ok := <-i.sigch; !ok {
+		return
+	}
+	log.Printf(
+		"Interrupted, finishing checks in progress; interrupt " +
+			"again to exit now",
+	)
+	i.cancel()
+	if _, ok := <-i.sigch; !ok {
+		return
+	}
+	log.Fatalf("Interrupted again, exiting")
+}
+
+/* Context returns a context which is done once we've been interrupted. */
+func (i *interrupter) Context() context.Context {
+	if nil == i {
+		return context.Background()
+	}
+	return i.ctx
+}
+
+/* Interrupted returns true if we've been interrupted. */
+func (i *interrupter) Interrupted() bool {
+	if nil == i {
+		return false
+	}
+	return nil != i.ctx.Err()
+}
+
+/* Stop stops watching for SIGINTs, which go back to killing the process. */
+func (i *interrupter) Stop() {
+	if nil == i {
+		return
+	}
+	signal.Stop(i.sigch)
+	close(i.sigch)
+}
@@ -0,0 +1,98 @@
+package main
+
+/*
+ * jsonlines.go
+ * Write results as JSON lines
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSON line event types
+const (
+	EventResult = "result"
+	EventRegion = "region"
+	EventRetry  = "retry"
+)
+
+/* jsonLines is a resultSink which writes each result as a JSON line, along
+with lines for the steps along the way to a result: finding a bucket's region
+and retrying a check.  A nil *jsonLines writes nothing.  It is safe to call
+jsonLines's methods from multiple goroutines. */
+type jsonLines struct {
+	l   sync.Mutex
+	enc *json.Encoder
+}
+
+/* jsonProgress is a line about a step along the way to a result. */
+type jsonProgress struct {
+	Event     string    `json:"event"`
+	Name      string    `json:"name"`
+	BucketURL string    `json:"bucket_url"`
+	Region    string    `json:"region,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Time      time.Time `json:"timestamp"`
+}
+
+/* newJSONLines returns a jsonLines which writes to w, or nil if enabled is
+false. */
+func newJSONLines(w io.Writer, enabled bool) *jsonLines {
+	if !enabled {
+		return nil
+	}
+	return &jsonLines{enc: json.NewEncoder(w)}
+}
+
+/* Send writes r as a result line. */
+func (j *jsonLines) Send(r Result) {
+	j.write(r.Name, struct {
+		Event string `json:"event"`
+		Result
+	}{EventResult, r})
+}
+
+/* Region writes a line noting that the bucket for cand, checked at
+bucketURL, is in the given region and will be checked there. */
+func (j *jsonLines) Region(cand candidate, bucketURL, region string) {
+	if nil == j {
+		return
+	}
+	j.write(cand.name, jsonProgress{
+		Event:     EventRegion,
+		Name:      cand.name,
+		BucketURL: bucketURL,
+		Region:    region,
+		Time:      time.Now(),
+	})
+}
+
+/* Retry writes a line noting that the check of cand at bucketURL will be
+retried, and why. */
+func (j *jsonLines) Retry(cand candidate, bucketURL, why string) {
+	if nil == j {
+		return
+	}
+	j.write(cand.name, jsonProgress{
+		Event:     EventRetry,
+		Name:      cand.name,
+		BucketURL: bucketURL,
+		Reason:    why,
+		Time:      time.Now(),
+	})
+}
+
+/* write writes v, a line about the name n, logging errors. */
+func (j *jsonLines) write(n string, v interface{}) {
+	j.l.Lock()
+	defer j.l.Unlock()
+	if err := j.enc.Encode(v); nil != err {
+		elog.Printf("[%v] Unable to write JSON line: %v", n, err)
+	}
+}
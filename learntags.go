@@ -0,0 +1,116 @@
+package main
+
+/*
+ * learntags.go
+ * Suggest tags from the names of found buckets
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* tagLearner counts the tokens in the names of buckets which exist, to
+suggest tags for a later run.  Tokens from the input names from which the
+buckets' names were generated, usually the organization's name, are left out.
+A nil *tagLearner learns nothing.  It is safe to call tagLearner's methods
+from multiple goroutines. */
+type tagLearner struct {
+	l       sync.Mutex
+	counts  map[string]int
+	exclude map[string]struct{}
+}
+
+/* newTagLearner returns a new tagLearner, or nil if enabled is false. */
+func newTagLearner(enabled bool) *tagLearner {
+	if !enabled {
+		return nil
+	}
+	return &tagLearner{
+		counts:  make(map[string]int),
+		exclude: make(map[string]struct{}),
+	}
+}
+
+/* tagTokens splits n on hyphens, dots, and underscores.  Tokens shorter than
+two characters aren't much use as tags and are skipped. */
+func tagTokens(n string) []string {
+	var ts []string
+	for _, t := range strings.FieldsFunc(strings.ToLower(n), isTagSep) {
+		if 2 <= len(t) {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+/* isTagSep returns true if r separates tokens in a bucket name. */
+func isTagSep(r rune) bool {
+	return '-' == r || '.' == r || '_' == r
+}
+
+/* Add notes that the bucket named n, generated from the input name input,
+exists.  Input may be the empty string if it's not known. */
+func (l *tagLearner) Add(n, input string) {
+	if nil == l {
+		return
+	}
+	l.l.Lock()
+	defer l.l.Unlock()
+	for _, t := range tagTokens(input) {
+		l.exclude[t] = struct{}{}
+	}
+	for _, t := range tagTokens(n) {
+		l.counts[t]++
+	}
+}
+
+/* Write writes the tokens seen, less the excluded ones, to the file named fn,
+one per line, most common first.  The file is truncated if it exists.  The
+number of tokens written is returned. */
+func (l *tagLearner) Write(fn string) (int, error) {
+	if nil == l {
+		return 0, nil
+	}
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	/* Rank the tokens */
+	var ts []string
+	for t := range l.counts {
+		if _, ok := l.exclude[t]; !ok {
+			ts = append(ts, t)
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if l.counts[ts[i]] != l.counts[ts[j]] {
+			return l.counts[ts[i]] > l.counts[ts[j]]
+		}
+		return ts[i] < ts[j]
+	})
+
+	/* Write them to the file */
+	f, err := os.Create(fn)
+	if nil != err {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	for _, t := range ts {
+		if _, err := fmt.Fprintln(w, t); nil != err {
+			f.Close()
+			return 0, err
+		}
+	}
+	if err := w.Flush(); nil != err {
+		f.Close()
+		return 0, err
+	}
+	return len(ts), f.Close()
+}
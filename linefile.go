@@ -0,0 +1,71 @@
+package main
+
+/*
+ * linefile.go
+ * Write unique lines to a file
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+/* lineFile writes lines to a file, skipping lines it's already written.  Each
+line is written as soon as it's received, so a killed process still leaves a
+usable file.  A nil *lineFile discards everything written to it.  It is safe
+to call lineFile's methods from multiple goroutines. */
+type lineFile struct {
+	l    sync.Mutex
+	f    *os.File
+	seen map[string]struct{}
+}
+
+/* newLineFile returns a lineFile which writes to the file named fn.  If
+appendTo is true, the file will be appended to, otherwise it will be
+truncated.  If fn is the empty string, newLineFile returns nil. */
+func newLineFile(fn string, appendTo bool) (*lineFile, error) {
+	if "" == fn {
+		return nil, nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE
+	if appendTo {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(fn, flags, 0644)
+	if nil != err {
+		return nil, err
+	}
+	return &lineFile{f: f, seen: make(map[string]struct{})}, nil
+}
+
+/* WriteLine writes s to the file followed by a newline, unless s has already
+been written. */
+func (l *lineFile) WriteLine(s string) error {
+	if nil == l {
+		return nil
+	}
+	l.l.Lock()
+	defer l.l.Unlock()
+	if _, ok := l.seen[s]; ok {
+		return nil
+	}
+	l.seen[s] = struct{}{}
+	_, err := fmt.Fprintln(l.f, s)
+	return err
+}
+
+/* Close closes the file. */
+func (l *lineFile) Close() error {
+	if nil == l {
+		return nil
+	}
+	l.l.Lock()
+	defer l.l.Unlock()
+	return l.f.Close()
+}
@@ -0,0 +1,192 @@
+package main
+
+/*
+ * listing.go
+ * List the contents of public buckets
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// LISTMAXBODY is the maximum number of bytes of a page of a bucket
+	// listing to read
+	LISTMAXBODY = 4 * 1024 * 1024
+
+	// LISTPAGESIZE is the most keys S3 will send in one page of a listing
+	LISTPAGESIZE = 1000
+)
+
+/* errListDenied is returned when a bucket can't be listed because it's not
+allowed, even though it seemed public. */
+var errListDenied = errors.New("listing denied")
+
+/* listedObject is an object in a bucket listing. */
+type listedObject struct {
+	Key          string
+	Size         int64
+	LastModified string
+}
+
+/* listPage is a page of a bucket listing. */
+type listPage struct {
+	Contents              []listedObject
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+/* listLimits bounds how much of a bucket is listed, and how quickly.  A zero
+maxKeys or timeout or a nil limiter or adaptive means no limit. */
+type listLimits struct {
+	maxKeys  int
+	timeout  time.Duration
+	limiter  *rateLimiter
+	adaptive *adaptiveLimiter
+}
+
+/* listBucket lists the public bucket at bucketURL a page at a time using
+client, calling f for each object.  Listing stops after lim's maxKeys objects
+or once lim's timeout has elapsed, in which case truncated will be true.
+Only one page is held in memory at once.  The number of objects passed to f
+is returned. */
+func listBucket(
+	client *http.Client,
+	bucketURL string,
+	lim listLimits,
+	f func(o listedObject),
+) (n int, truncated bool, err error) {
+	ctx := context.Background()
+	if 0 != lim.timeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lim.timeout)
+		defer cancel()
+	}
+
+	var token string
+	for {
+		/* Don't ask for more than we want */
+		ps := LISTPAGESIZE
+		if 0 != lim.maxKeys && lim.maxKeys-n < ps {
+			ps = lim.maxKeys - n
+		}
+		if !waitTurn(ctx, lim.limiter, lim.adaptive) {
+			return n, true, nil
+		}
+		p, err := listBucketPage(ctx, client, bucketURL, token, ps)
+		if nil != err {
+			/* Running out of time just means we stop */
+			if nil != ctx.Err() {
+				return n, true, nil
+			}
+			return n, false, err
+		}
+		for _, o := range p.Contents {
+			f(o)
+			n++
+		}
+		if !p.IsTruncated || "" == p.NextContinuationToken {
+			return n, false, nil
+		}
+		if 0 != lim.maxKeys && lim.maxKeys <= n {
+			return n, true, nil
+		}
+		token = p.NextContinuationToken
+	}
+}
+
+/* listBucketPage gets the page of up to max keys of the listing of the bucket
+at bucketURL starting at the continuation token, which may be the empty string
+for the first page. */
+func listBucketPage(
+	ctx context.Context,
+	client *http.Client,
+	bucketURL string,
+	token string,
+	max int,
+) (listPage, error) {
+	var p listPage
+
+	/* Work out which page to ask for */
+	u, err := url.Parse(bucketURL)
+	if nil != err {
+		return p, err
+	}
+	q := u.Query()
+	q.Set("list-type", "2")
+	q.Set("max-keys", strconv.Itoa(max))
+	if "" != token {
+		q.Set("continuation-token", token)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if nil != err {
+		return p, err
+	}
+
+	/* Get it */
+	res, err := client.Do(req.WithContext(ctx))
+	if nil != err {
+		return p, err
+	}
+	defer res.Body.Close()
+	if http.StatusForbidden == res.StatusCode {
+		return p, errListDenied
+	}
+	if http.StatusOK != res.StatusCode {
+		return p, fmt.Errorf("unexpected response %v", res.Status)
+	}
+	if err := xml.NewDecoder(io.LimitReader(
+		res.Body,
+		LISTMAXBODY,
+	)).Decode(&p); nil != err {
+		return p, fmt.Errorf("decoding listing: %v", err)
+	}
+	return p, nil
+}
+
+/* logBucketKeys logs the key and size of each object in the public bucket
+for the candidate cand at bucketURL, within conf's listing limits. */
+func logBucketKeys(cand candidate, bucketURL string, conf *checkConfig) {
+	n := cand.name
+	nk, truncated, err := listBucket(
+		conf.client,
+		bucketURL,
+		conf.listLim,
+		func(o listedObject) {
+			conf.slog.Printf(
+				"[%v] Key: %v (%v bytes)",
+				n,
+				o.Key,
+				o.Size,
+			)
+		},
+	)
+	if errors.Is(err, errListDenied) {
+		log.Printf("[%v] Listing denied (%v)", n, bucketURL)
+		return
+	} else if nil != err {
+		elog.Printf("[%v] Unable to list %v: %v", n, bucketURL, err)
+		return
+	}
+	switch {
+	case truncated:
+		log.Printf("[%v] Listed first %v objects", n, nk)
+	case 1 == nk:
+		log.Printf("[%v] Listed 1 object", n)
+	default:
+		log.Printf("[%v] Listed %v objects", n, nk)
+	}
+}
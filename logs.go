@@ -0,0 +1,27 @@
+package main
+
+/*
+ * logs.go
+ * Separate streams for findings, information, and errors
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"log"
+	"os"
+)
+
+/* elog logs errors.  Findings go to main's slog and everything else goes to
+the standard logger. */
+var elog = log.New(os.Stderr, "", log.LstdFlags)
+
+/* openStream opens the file named fn for appending, creating it if need be,
+or returns def if fn is the empty string or - (for the default). */
+func openStream(fn string, def *os.File) (*os.File, error) {
+	if "" == fn || "-" == fn {
+		return def, nil
+	}
+	return os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
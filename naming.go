@@ -0,0 +1,181 @@
+package main
+
+/*
+ * naming.go
+ * Bucket naming rules
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+/* namingRules describes which names a storage provider allows. */
+type namingRules struct {
+	/* chars are the characters allowed in a name.  If chars doesn't
+	contain a dot, dots will be changed to hyphens. */
+	chars string
+
+	/* lower causes names to be lowercased before sanitization */
+	lower bool
+
+	/* maxLabelLen is the maximum length of a dot-separated label */
+	maxLabelLen int
+
+	/* maxLen is the maximum length of an entire name, or 0 for no
+	limit */
+	maxLen int
+
+	/* minLen is the minimum length of an entire name */
+	minLen int
+}
+
+// NAMINGPRESETS are the built-in naming rules for various providers
+var NAMINGPRESETS = map[string]namingRules{
+	"aws": {
+		chars:       NAMECHARS,
+		maxLabelLen: MAXLABELLEN,
+		maxLen:      MAXNAMELEN,
+		minLen:      3,
+	},
+	"gcs": {
+		chars:       NAMECHARS + "_",
+		lower:       true,
+		maxLabelLen: 63,
+		maxLen:      222,
+		minLen:      3,
+	},
+	"azure": {
+		chars:       "abcdefghijklmnopqrstuvwxyz0123456789-",
+		lower:       true,
+		maxLabelLen: 63,
+		maxLen:      63,
+		minLen:      3,
+	},
+}
+
+/* getNamingRules returns the rules for the named preset, with the allowed
+characters replaced with chars and the maximum label length replaced with
+maxLabelLen, if they're not the empty string and 0, respectively. */
+func getNamingRules(
+	preset string,
+	chars string,
+	maxLabelLen int,
+) (namingRules, error) {
+	r, ok := NAMINGPRESETS[preset]
+	if !ok {
+		ps := make([]string, 0, len(NAMINGPRESETS))
+		for p := range NAMINGPRESETS {
+			ps = append(ps, p)
+		}
+		sort.Strings(ps)
+		return namingRules{}, fmt.Errorf(
+			"unknown preset %q (known presets: %v)",
+			preset,
+			strings.Join(ps, ", "),
+		)
+	}
+	if "" != chars {
+		r.chars = chars
+	}
+	if 0 != maxLabelLen {
+		r.maxLabelLen = maxLabelLen
+	}
+	return r, nil
+}
+
+/* sanitize removes disallowed characters from name. */
+func (r namingRules) sanitize(name string) string {
+	if r.lower {
+		name = strings.ToLower(name)
+	}
+	noDots := !strings.ContainsRune(r.chars, '.')
+	return strings.Map(func(c rune) rune {
+		if noDots && '.' == c {
+			c = '-'
+		}
+		if !strings.ContainsRune(r.chars, c) {
+			return -1
+		}
+		return c
+	}, name)
+}
+
+/* normalize returns name as it'll be checked: sanitized and without leading
+or trailing dots. */
+func (r namingRules) normalize(name string) string {
+	return strings.Trim(r.sanitize(name), ".")
+}
+
+/* valid returns true if name is allowed by r. */
+func (r namingRules) valid(name string) bool {
+	if 0 != r.maxLen && r.maxLen < len(name) {
+		return false
+	}
+	for _, c := range name {
+		if !strings.ContainsRune(r.chars, c) {
+			return false
+		}
+	}
+	return true
+}
+
+/* problem returns why name isn't a usable bucket name under r, or the empty
+string if it is.  Unlike valid, problem applies all of the rules, not just
+those needed to generate names. */
+func (r namingRules) problem(name string) string {
+	/* Length */
+	if len(name) < r.minLen {
+		return fmt.Sprintf("shorter than %v characters", r.minLen)
+	}
+	if 0 != r.maxLen && r.maxLen < len(name) {
+		return fmt.Sprintf("longer than %v characters", r.maxLen)
+	}
+
+	/* Characters */
+	for _, c := range name {
+		if !strings.ContainsRune(r.chars, c) {
+			return fmt.Sprintf("character %q not allowed", c)
+		}
+	}
+
+	/* Not an IP address */
+	if nil != net.ParseIP(name) {
+		return "formatted as an IP address"
+	}
+
+	/* Labels */
+	for _, l := range strings.Split(name, ".") {
+		if "" == l {
+			return "empty label"
+		}
+		if r.maxLabelLen < len(l) {
+			return fmt.Sprintf(
+				"label %q longer than %v characters",
+				l,
+				r.maxLabelLen,
+			)
+		}
+		if !isAlnum(l[0]) || !isAlnum(l[len(l)-1]) {
+			return fmt.Sprintf(
+				"label %q doesn't start and end with a "+
+					"letter or number",
+				l,
+			)
+		}
+	}
+
+	return ""
+}
+
+/* isAlnum returns true if c is an ASCII letter or number. */
+func isAlnum(c byte) bool {
+	return ('a' <= c && 'z' >= c) ||
+		('A' <= c && 'Z' >= c) ||
+		('0' <= c && '9' >= c)
+}
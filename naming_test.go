@@ -0,0 +1,138 @@
+package main
+
+/*
+ * naming_test.go
+ * Tests for the rules for bucket names
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"strings"
+	"testing"
+)
+
+/* namingCase is a name and how a preset should treat it. */
+type namingCase struct {
+	name       string
+	normalized string
+	problem    bool /* Whether the normalized name has a problem */
+}
+
+/* testNamingPreset checks that the named preset treats each case's name as
+it should. */
+func testNamingPreset(t *testing.T, preset string, cases []namingCase) {
+	r, err := getNamingRules(preset, "", 0)
+	if nil != err {
+		t.Fatalf("Getting rules: %v", err)
+	}
+	for _, c := range cases {
+		got := r.normalize(c.name)
+		if c.normalized != got {
+			t.Errorf(
+				"%q: normalized to %q, want %q",
+				c.name,
+				got,
+				c.normalized,
+			)
+			continue
+		}
+		if p := r.problem(got); c.problem != ("" != p) {
+			t.Errorf(
+				"%q: problem %q, want problem: %v",
+				got,
+				p,
+				c.problem,
+			)
+		}
+	}
+}
+
+func TestNamingPresetAWS(t *testing.T) {
+	testNamingPreset(t, "aws", []namingCase{
+		{"foo.bar", "foo.bar", false},
+		{"..foo-bar..", "foo-bar", false},
+		{"foo_bar", "foobar", false},
+		{"fooBAR", "foo", false},
+		{"ab", "ab", true},
+		{"foo..bar", "foo..bar", true},
+		{"-foo.bar", "-foo.bar", true},
+		{"foo.bar-", "foo.bar-", true},
+		{"192.168.0.1", "192.168.0.1", true},
+		{
+			strings.Repeat("a", 63),
+			strings.Repeat("a", 63),
+			false,
+		},
+		{
+			strings.Repeat("a", 64),
+			strings.Repeat("a", 64),
+			true,
+		},
+		{
+			"foo." + strings.Repeat("a", 60),
+			"foo." + strings.Repeat("a", 60),
+			true,
+		},
+	})
+}
+
+func TestNamingPresetGCS(t *testing.T) {
+	testNamingPreset(t, "gcs", []namingCase{
+		{"foo.bar", "foo.bar", false},
+		{"Foo_Bar", "foo_bar", false},
+		{"_foo", "_foo", true},
+		{"ab", "ab", true},
+		{strings.Repeat("a", 63), strings.Repeat("a", 63), false},
+		{strings.Repeat("a", 64), strings.Repeat("a", 64), true},
+		{
+			strings.Repeat("abc.", 55) + "ab",
+			strings.Repeat("abc.", 55) + "ab",
+			false,
+		},
+		{
+			strings.Repeat("abc.", 55) + "abc",
+			strings.Repeat("abc.", 55) + "abc",
+			true,
+		},
+	})
+}
+
+func TestNamingPresetAzure(t *testing.T) {
+	testNamingPreset(t, "azure", []namingCase{
+		{"foobar", "foobar", false},
+		{"Foo.Bar", "foo-bar", false},
+		{"foo_bar", "foobar", false},
+		{"ab", "ab", true},
+		{"-foo", "-foo", true},
+		{strings.Repeat("a", 63), strings.Repeat("a", 63), false},
+		{strings.Repeat("a", 64), strings.Repeat("a", 64), true},
+	})
+}
+
+func TestNamingRulesValid(t *testing.T) {
+	r := NAMINGPRESETS["aws"]
+	for _, n := range []string{"foo_bar", "Foo", "foo bar"} {
+		if r.valid(n) {
+			t.Errorf("%q: valid with disallowed characters", n)
+		}
+	}
+}
+
+func TestGetNamingRules(t *testing.T) {
+	r, err := getNamingRules("aws", "abc_", 5)
+	if nil != err {
+		t.Fatalf("Overriding aws: %v", err)
+	}
+	if "abc_" != r.chars || 5 != r.maxLabelLen {
+		t.Errorf(
+			"Overrides not used: chars %q, maxLabelLen %v",
+			r.chars,
+			r.maxLabelLen,
+		)
+	}
+	if _, err := getNamingRules("kittens", "", 0); nil == err {
+		t.Errorf("Unknown preset didn't return an error")
+	}
+}
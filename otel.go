@@ -0,0 +1,138 @@
+package main
+
+/*
+ * otel.go
+ * OpenTelemetry tracing
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTELSHUTDOWNTIMEOUT is how long to wait for the last spans to be exported
+const OTELSHUTDOWNTIMEOUT = 10 * time.Second
+
+/* otelTracer makes spans.  Until startOTel is called, its spans go
+nowhere. */
+var otelTracer = otel.Tracer("github.com/magisterquis/s3finder")
+
+/* startOTel starts exporting spans via OTLP over HTTP to the collector at the
+URL u.  The returned function flushes any remaining spans and stops the
+exporter.  If u is the empty string, spans aren't exported and the returned
+function does nothing. */
+func startOTel(u string) (func(), error) {
+	if "" == u {
+		return func() {}, nil
+	}
+	exp, err := otlptracehttp.New(
+		context.Background(),
+		otlptracehttp.WithEndpointURL(u),
+	)
+	if nil != err {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("s3finder"),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	return func() {
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			OTELSHUTDOWNTIMEOUT,
+		)
+		defer cancel()
+		if err := tp.Shutdown(ctx); nil != err {
+			elog.Printf("Error exporting last spans: %v", err)
+		}
+	}, nil
+}
+
+/* startInputSpan starts a span for generating candidates from the input name
+n. */
+func startInputSpan(n string) trace.Span {
+	_, span := otelTracer.Start(
+		context.Background(),
+		"input",
+		trace.WithAttributes(attribute.String("s3finder.input", n)),
+	)
+	return span
+}
+
+/* startSubdomainSpan starts a span for asking the subdomain source src for
+subdomains of n. */
+func startSubdomainSpan(src subdomainSource, n string) trace.Span {
+	_, span := otelTracer.Start(
+		context.Background(),
+		"subdomains",
+		trace.WithAttributes(
+			attribute.String(
+				"s3finder.subdomain_source",
+				src.String(),
+			),
+			attribute.String("s3finder.input", n),
+		),
+	)
+	return span
+}
+
+/* startCandidateSpan starts a span for checking cand, as a child of the span
+for its input, if it has one. */
+func startCandidateSpan(
+	ctx context.Context,
+	cand candidate,
+) (context.Context, trace.Span) {
+	return otelTracer.Start(
+		trace.ContextWithSpanContext(ctx, cand.span),
+		"check",
+		trace.WithAttributes(
+			attribute.String("s3finder.name", cand.name),
+			attribute.String("s3finder.source", cand.source),
+		),
+	)
+}
+
+/* spanRequest notes, in the span in ctx, that req to the endpoints named ep
+got res or err after lat. */
+func spanRequest(
+	ctx context.Context,
+	ep string,
+	req *http.Request,
+	res *http.Response,
+	err error,
+	lat time.Duration,
+) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	as := []attribute.KeyValue{
+		attribute.String("s3finder.endpoints", ep),
+		attribute.String("url.full", req.URL.String()),
+		attribute.Int64("s3finder.latency_ms", lat.Milliseconds()),
+	}
+	if nil != err {
+		as = append(as, attribute.String("error.message", err.Error()))
+	} else {
+		as = append(as, attribute.Int(
+			"http.response.status_code",
+			res.StatusCode,
+		))
+	}
+	span.AddEvent("request", trace.WithAttributes(as...))
+}
@@ -0,0 +1,154 @@
+package main
+
+/*
+ * owner.go
+ * Work out who owns buckets
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OWNERMAXBODY is the maximum number of bytes of a listing to read looking
+// for an object's owner
+const OWNERMAXBODY = 64 * 1024
+
+/* bucketOwner is the canonical user who owns a bucket or one of its
+objects. */
+type bucketOwner struct {
+	ID          string
+	DisplayName string
+}
+
+/* String returns o's display name, if it has one, or its ID. */
+func (o bucketOwner) String() string {
+	if "" != o.DisplayName {
+		return o.DisplayName
+	}
+	return o.ID
+}
+
+/* ownerFromACL returns the owner in the ACL document doc.  The owner's ID is
+the empty string if doc has no owner. */
+func ownerFromACL(doc string) bucketOwner {
+	var acl struct {
+		Owner bucketOwner
+	}
+	if err := xml.Unmarshal([]byte(doc), &acl); nil != err {
+		return bucketOwner{}
+	}
+	return acl.Owner
+}
+
+/* ownerFromListing returns the owner of the first object with one in the
+first OWNERMAXBODY bytes of a bucket listing read from r.  Objects are
+usually, but not always, owned by their bucket's owner.  The owner's ID is
+the empty string if no object has an owner. */
+func ownerFromListing(r io.Reader) bucketOwner {
+	dec := xml.NewDecoder(io.LimitReader(r, OWNERMAXBODY))
+	for {
+		t, err := dec.Token()
+		if nil != err {
+			return bucketOwner{}
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok || "Owner" != se.Name.Local {
+			continue
+		}
+		var o bucketOwner
+		if err := dec.DecodeElement(&o, &se); nil != err {
+			return bucketOwner{}
+		}
+		if "" != o.ID {
+			return o
+		}
+	}
+}
+
+/* ownerGroups is a resultSink which groups the buckets it's sent by their
+owners.  Buckets with no known owner are ignored.  A nil *ownerGroups ignores
+everything.  It is safe to call ownerGroups' methods from multiple
+goroutines. */
+type ownerGroups struct {
+	l      sync.Mutex
+	names  map[string]string              /* Display names, by ID */
+	owners map[string]map[string]struct{} /* Buckets, by owner ID */
+}
+
+/* newOwnerGroups returns a new ownerGroups, or nil if enabled is false. */
+func newOwnerGroups(enabled bool) *ownerGroups {
+	if !enabled {
+		return nil
+	}
+	return &ownerGroups{
+		names:  make(map[string]string),
+		owners: make(map[string]map[string]struct{}),
+	}
+}
+
+/* Send notes r's bucket's owner, if it's known. */
+func (g *ownerGroups) Send(r Result) {
+	if nil == g || "" == r.OwnerID {
+		return
+	}
+	g.l.Lock()
+	defer g.l.Unlock()
+	if "" != r.OwnerName {
+		g.names[r.OwnerID] = r.OwnerName
+	}
+	bs, ok := g.owners[r.OwnerID]
+	if !ok {
+		bs = make(map[string]struct{})
+		g.owners[r.OwnerID] = bs
+	}
+	bs[r.Name] = struct{}{}
+}
+
+/* Log logs the buckets owned by each owner, owners with the most buckets
+first. */
+func (g *ownerGroups) Log() {
+	if nil == g {
+		return
+	}
+	g.l.Lock()
+	defer g.l.Unlock()
+	if 0 == len(g.owners) {
+		log.Printf("No bucket owners found")
+		return
+	}
+
+	/* Biggest owners first */
+	ids := make([]string, 0, len(g.owners))
+	for id := range g.owners {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ni, nj := len(g.owners[ids[i]]), len(g.owners[ids[j]])
+		if ni != nj {
+			return ni > nj
+		}
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		bs := make([]string, 0, len(g.owners[id]))
+		for b := range g.owners[id] {
+			bs = append(bs, b)
+		}
+		sort.Strings(bs)
+		o := id
+		if n, ok := g.names[id]; ok {
+			o = fmt.Sprintf("%v (%v)", n, id)
+		}
+		log.Printf("Owner %v: %v", o, strings.Join(bs, ", "))
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+/*
+ * passivedns.go
+ * Find subdomains with passive DNS
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// SECURITYTRAILSURL is the URL pattern for querying SecurityTrails
+	// for subdomains
+	SECURITYTRAILSURL = "https://api.securitytrails.com/v1/domain/%v/subdomains"
+
+	// PASSIVEDNSTIMEOUT is how long to wait for a passive DNS query
+	PASSIVEDNSTIMEOUT = time.Minute
+
+	// PASSIVEDNSMAXBYTES is the maximum number of bytes to read from a
+	// single passive DNS response
+	PASSIVEDNSMAXBYTES = 64 * 1024 * 1024
+)
+
+/* securityTrailsSource is a subdomainSource which queries SecurityTrails'
+passive DNS data using the API key key. */
+type securityTrailsSource struct {
+	key    string
+	client *http.Client
+}
+
+/* newSecurityTrailsSource returns a securityTrailsSource which uses the API
+key k. */
+func newSecurityTrailsSource(k string) *securityTrailsSource {
+	return &securityTrailsSource{
+		key:    k,
+		client: &http.Client{Timeout: PASSIVEDNSTIMEOUT},
+	}
+}
+
+/* Subdomains queries SecurityTrails for subdomains of d. */
+func (s *securityTrailsSource) Subdomains(d string) ([]string, error) {
+	req, err := http.NewRequest(
+		"GET",
+		fmt.Sprintf(SECURITYTRAILSURL, url.PathEscape(d)),
+		nil,
+	)
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Set("APIKEY", s.key)
+	req.Header.Set("Accept", "application/json")
+	res, err := s.client.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	/* 404's mean no names */
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return []string{}, nil
+	default:
+		return nil, fmt.Errorf("unexpected response %v", res.Status)
+	}
+
+	/* SecurityTrails just sends the leftmost labels */
+	var r struct {
+		Subdomains []string `json:"subdomains"`
+	}
+	if err := json.NewDecoder(io.LimitReader(
+		res.Body,
+		PASSIVEDNSMAXBYTES,
+	)).Decode(&r); nil != err {
+		return nil, err
+	}
+	ns := make([]string, 0, len(r.Subdomains))
+	for _, sd := range r.Subdomains {
+		sd = strings.Trim(sd, ".")
+		if "" == sd {
+			continue
+		}
+		ns = append(ns, sd+"."+d)
+	}
+	return ns, nil
+}
+
+/* String returns "SecurityTrails". */
+func (s *securityTrailsSource) String() string { return "SecurityTrails" }
+
+/* Source returns SourcePassiveDNS. */
+func (s *securityTrailsSource) Source() string { return SourcePassiveDNS }
@@ -0,0 +1,57 @@
+package main
+
+/*
+ * pause.go
+ * Pause and resume checks
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"log"
+	"sync"
+)
+
+/* pauser lets checks be paused and resumed.  A nil *pauser never pauses.  It
+is safe to call pauser's methods from multiple goroutines. */
+type pauser struct {
+	l      sync.Mutex
+	c      *sync.Cond
+	paused bool
+}
+
+/* newPauser returns a new, unpaused, pauser. */
+func newPauser() *pauser {
+	p := &pauser{}
+	p.c = sync.NewCond(&p.l)
+	return p
+}
+
+/* Toggle pauses p if it's not paused, and resumes it if it is. */
+func (p *pauser) Toggle() {
+	if nil == p {
+		return
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.paused = !p.paused
+	if p.paused {
+		log.Printf("Paused")
+		return
+	}
+	log.Printf("Resumed")
+	p.c.Broadcast()
+}
+
+/* Wait waits until p isn't paused. */
+func (p *pauser) Wait() {
+	if nil == p {
+		return
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	for p.paused {
+		p.c.Wait()
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+/*
+ * pause_unix.go
+ * Pause and resume checks with SIGUSR1
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+/* watchPauseSignal toggles p every time we get a SIGUSR1. */
+func watchPauseSignal(p *pauser) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	go func() {
+		for range ch {
+			p.Toggle()
+		}
+	}()
+}
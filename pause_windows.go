@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package main
+
+/*
+ * pause_windows.go
+ * No pausing on Windows
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+/* watchPauseSignal does nothing, as Windows has no SIGUSR1. */
+func watchPauseSignal(p *pauser) {}
@@ -0,0 +1,131 @@
+package main
+
+/*
+ * prefix.go
+ * List forbidden buckets under specific prefixes
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+/* readPrefixes returns the key prefixes in the file named fn, one per line.
+Blank lines and lines starting with a # are skipped, as are leading slashes,
+which keys don't have. */
+func readPrefixes(fn string) ([]string, error) {
+	f, err := os.Open(fn)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ps []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		/* Skip blank lines and comments */
+		if "" == l || strings.HasPrefix(l, "#") {
+			continue
+		}
+		if l = strings.TrimLeft(l, "/"); "" != l {
+			ps = append(ps, l)
+		}
+	}
+	if err := s.Err(); nil != err {
+		return nil, err
+	}
+	return ps, nil
+}
+
+/* readablePrefixes returns the prefixes in conf.prefixes under which the
+bucket cand can be listed, though orig, a request to list the whole bucket,
+was forbidden. */
+func readablePrefixes(
+	cand candidate,
+	orig *http.Request,
+	worker uint,
+	conf *checkConfig,
+) []string {
+	var ps []string
+	for _, p := range conf.prefixes {
+		if nil != orig.Context().Err() {
+			break
+		}
+		if prefixReadable(cand, orig, p, worker, conf) {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+/* prefixReadable returns true if the bucket cand can be listed under the
+prefix p, using the same URL and Host as orig. */
+func prefixReadable(
+	cand candidate,
+	orig *http.Request,
+	p string,
+	worker uint,
+	conf *checkConfig,
+) bool {
+	n := cand.name
+
+	/* Roll the request */
+	u := *orig.URL
+	u.RawQuery = "prefix=" + url.QueryEscape(p)
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if nil != err {
+		elog.Printf("[%v] Unable to make request for %q: %v", n, p, err)
+		return false
+	}
+	req.Host = orig.Host
+	req = req.WithContext(orig.Context())
+
+	/* See what the bucket says */
+	if !conf.wait(req.Context()) {
+		return false
+	}
+	start := time.Now()
+	res, err := conf.client.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add("prefix")
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
+	if nil != err {
+		if nil == req.Context().Err() {
+			elog.Printf(
+				"[%v] Error listing under %q: %v",
+				n,
+				p,
+				err,
+			)
+		}
+		return false
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, LISTMAXBODY))
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true
+	case http.StatusForbidden:
+		return false
+	default:
+		log.Printf(
+			"[%v] Unexpected response listing under %q: %v",
+			n,
+			p,
+			res.Status,
+		)
+		return false
+	}
+}
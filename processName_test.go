@@ -0,0 +1,239 @@
+package main
+
+/*
+ * processName_test.go
+ * Tests for turning a name into candidate bucket names
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/* newTestNameConfig returns a nameConfig with the AWS naming rules and the
+given tags and dates. */
+func newTestNameConfig(t *testing.T, tags, dates []string) *nameConfig {
+	rules, err := getNamingRules("aws", "", 0)
+	if nil != err {
+		t.Fatalf("Getting naming rules: %v", err)
+	}
+	suffixes, err := newSuffixList("", "")
+	if nil != err {
+		t.Fatalf("Making public suffix list: %v", err)
+	}
+	return &nameConfig{
+		tags:     tags,
+		dates:    dates,
+		seen:     newSeenNames(1024),
+		rules:    rules,
+		domains:  make(map[string]struct{}),
+		suffixes: suffixes,
+		totals:   &runTotals{},
+	}
+}
+
+/* processedNames returns the names processName generates from the literal
+name n according to conf, mapped to their sources. */
+func processedNames(n string, conf *nameConfig) map[string]string {
+	var (
+		ch   = make(chan candidate)
+		done = make(chan struct{})
+	)
+	go func() {
+		defer close(ch)
+		processName(ch, candidate{
+			name:   n,
+			source: SourceLiteral,
+			input:  n,
+		}, conf)
+	}()
+	got := make(map[string]string)
+	go func() {
+		defer close(done)
+		for c := range ch {
+			if _, ok := got[c.name]; ok {
+				got[c.name] = "duplicate"
+				continue
+			}
+			got[c.name] = c.source
+		}
+	}()
+	<-done
+	return got
+}
+
+func TestProcessName(t *testing.T) {
+	for _, c := range []struct {
+		rule  string
+		name  string
+		tags  []string
+		dates []string
+		want  map[string]string
+	}{{
+		rule: "bare label",
+		name: "foo",
+		want: map[string]string{"foo": SourceLiteral},
+	}, {
+		rule: "sanitized",
+		name: "Foo_bar",
+		want: map[string]string{"oobar": SourceLiteral},
+	}, {
+		rule: "leading and trailing dots removed, runs compressed",
+		name: ".foo..bar.",
+		want: map[string]string{
+			"foo.bar":  SourceLiteral,
+			"foo--bar": SourceDotSwap,
+		},
+	}, {
+		rule: "dots to hyphens",
+		name: "foo.bar",
+		want: map[string]string{
+			"foo.bar": SourceLiteral,
+			"foo-bar": SourceDotSwap,
+		},
+	}, {
+		rule: "dots and hyphens changed and swapped",
+		name: "a-b.c",
+		want: map[string]string{
+			"a-b.c": SourceLiteral,
+			"a-b-c": SourceDotSwap,
+			"a.b.c": SourceDotSwap,
+			"a.b-c": SourceDotSwap,
+		},
+	}, {
+		rule: "label too long",
+		name: "x." + strings.Repeat("a", MAXLABELLEN+1),
+		want: map[string]string{},
+	}, {
+		rule: "nothing left",
+		name: "...",
+		want: map[string]string{},
+	}, {
+		rule: "six tag combinations",
+		name: "foo",
+		tags: []string{"dev"},
+		want: map[string]string{
+			"foo":     SourceLiteral,
+			"devfoo":  SourceTagPrefix,
+			"dev.foo": SourceTagPrefix,
+			"dev-foo": SourceTagPrefix,
+			"foodev":  SourceTagSuffix,
+			"foo.dev": SourceTagSuffix,
+			"foo-dev": SourceTagSuffix,
+		},
+	}, {
+		rule: "tags with dots changed",
+		name: "a.b",
+		tags: []string{"t"},
+		want: map[string]string{
+			"a.b":   SourceLiteral,
+			"a-b":   SourceDotSwap,
+			"ta.b":  SourceTagPrefix,
+			"t.a.b": SourceTagPrefix,
+			"t-a.b": SourceTagPrefix,
+			"a.bt":  SourceTagSuffix,
+			"a.b.t": SourceTagSuffix,
+			"a.b-t": SourceTagSuffix,
+			"ta-b":  SourceDotSwap,
+			"a-bt":  SourceDotSwap,
+			"t-a-b": SourceDotSwap,
+			"a-b-t": SourceDotSwap,
+			"t.a-b": SourceDotSwap,
+			"a-b.t": SourceDotSwap,
+		},
+	}, {
+		rule:  "dates",
+		name:  "foo",
+		dates: []string{"2026"},
+		want: map[string]string{
+			"foo":      SourceLiteral,
+			"foo2026":  SourceDateSuffix,
+			"foo-2026": SourceDateSuffix,
+			"foo.2026": SourceDotSwap,
+		},
+	}} {
+		got := processedNames(c.name, newTestNameConfig(
+			t,
+			c.tags,
+			c.dates,
+		))
+		if !reflect.DeepEqual(c.want, got) {
+			t.Errorf(
+				"%v (%q): got %v, want %v",
+				c.rule,
+				c.name,
+				got,
+				c.want,
+			)
+		}
+	}
+}
+
+func TestProcessNameSeen(t *testing.T) {
+	conf := newTestNameConfig(t, []string{"dev"}, nil)
+	if 0 == len(processedNames("foo", conf)) {
+		t.Fatalf("First time seeing name generated nothing")
+	}
+
+	/* Seen names aren't tried again */
+	if got := processedNames("foo", conf); 0 != len(got) {
+		t.Errorf("Seen name generated %v", got)
+	}
+
+	/* Unless they're inputs we always want checked */
+	conf.keepOriginal = true
+	want := map[string]string{"foo": SourceLiteral}
+	if got := processedNames("foo", conf); !reflect.DeepEqual(want, got) {
+		t.Errorf("Seen original generated %v, want %v", got, want)
+	}
+}
+
+func BenchmarkSendWithDotsAndHyphensChanged(b *testing.B) {
+	rules, err := getNamingRules("aws", "", 0)
+	if nil != err {
+		b.Fatalf("Getting naming rules: %v", err)
+	}
+	c := candidate{name: "assets.example.com", input: "assets.example.com"}
+	for _, bc := range []struct {
+		name string
+		ns   []candidate
+	}{{
+		name: "bare_label",
+		ns:   []candidate{c.derive("example", SourceLiteral)},
+	}, {
+		name: "dotted",
+		ns:   []candidate{c},
+	}, {
+		name: "tagged",
+		ns: []candidate{
+			c.derive("dev"+c.name, SourceTagPrefix),
+			c.derive(c.name+"dev", SourceTagSuffix),
+			c.derive("dev."+c.name, SourceTagPrefix),
+			c.derive(c.name+".dev", SourceTagSuffix),
+			c.derive("dev-"+c.name, SourceTagPrefix),
+			c.derive(c.name+"-dev", SourceTagSuffix),
+		},
+	}, {
+		name: "many_dots",
+		ns: []candidate{c.derive(
+			strings.Repeat("a..-", 32)+"a",
+			SourceLiteral,
+		)},
+	}} {
+		b.Run(bc.name, func(b *testing.B) {
+			/* At most four names are sent per candidate */
+			ch := make(chan candidate, 4*len(bc.ns))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sendWithDotsAndHyphensChanged(ch, bc.ns, rules)
+				for 0 != len(ch) {
+					<-ch
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+/*
+ * provider.go
+ * Places buckets might be
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"strings"
+)
+
+/* provider is somewhere a bucket might be, e.g. a set of S3 endpoints. */
+type provider interface {
+	/* Check checks whether there's a bucket for cand, reporting what it
+	finds to conf's sinks.  A single check may report nothing or more than
+	one thing, so results aren't returned.  The worker number identifies
+	the checker in the trace.  The check is abandoned if ctx is done. */
+	Check(
+		ctx context.Context,
+		cand candidate,
+		worker uint,
+		conf *checkConfig,
+	)
+
+	/* String names the provider, for statistics and logs */
+	String() string
+}
+
+/* s3Provider is a provider which checks a set of S3 endpoints, or endpoints
+for something which works like S3. */
+type s3Provider struct {
+	ep endpoints
+
+	/* rules, if not nil, are the provider's naming rules.  Names they
+	don't allow aren't checked. */
+	rules *namingRules
+}
+
+/* s3Providers returns an s3Provider for each set of endpoints in eps. */
+func s3Providers(eps []endpoints) []provider {
+	ps := make([]provider, 0, len(eps))
+	for _, ep := range eps {
+		ps = append(ps, s3Provider{ep: ep})
+	}
+	return ps
+}
+
+/* Check checks cand against p's endpoints, starting in their start region, if
+they have one, or the default region otherwise.
+Virtual-hosted-style endpoints are skipped for names with dots, as the name
+becomes more than one label of the host, which S3's wildcard certificates
+don't cover.  So are names p's naming rules don't allow. */
+func (p s3Provider) Check(
+	ctx context.Context,
+	cand candidate,
+	worker uint,
+	conf *checkConfig,
+) {
+	if p.ep.virtual && strings.Contains(cand.name, ".") {
+		return
+	}
+	if nil != p.rules && "" != p.rules.problem(cand.name) {
+		return
+	}
+	check(ctx, cand, p.ep.start, p.ep, MAXRECURSION, worker, conf)
+}
+
+/* String returns the name of p's endpoints. */
+func (p s3Provider) String() string { return p.ep.name }
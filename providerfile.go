@@ -0,0 +1,195 @@
+package main
+
+/*
+ * providerfile.go
+ * Providers defined in a file
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PROVIDERSTATUSES maps the meanings a provider file may give to HTTP status
+// codes to the status code S3 sends for the same thing
+var PROVIDERSTATUSES = map[string]int{
+	StatusPublic:    200,
+	StatusForbidden: 403,
+	StatusNotBucket: 404,
+	StatusRedirect:  302, /* Handled like S3's temporary redirects */
+}
+
+/* providerDef is a provider's definition in a provider file. */
+type providerDef struct {
+	/* Name names the provider in statistics and logs */
+	Name string `json:"name"`
+
+	/* URL is the URL to use when the region isn't known, with a
+	placeholder for the bucket name if Addressing is virtual */
+	URL string `json:"url"`
+
+	/* RegionalURL, if set, is the URL to use for a region, with a
+	placeholder for the region, after one for the bucket name if
+	Addressing is virtual */
+	RegionalURL string `json:"regional_url"`
+
+	/* Addressing is either path (the default), for which the bucket
+	name is the first part of the URL's path, or virtual, for which it's
+	part of the URL's host */
+	Addressing string `json:"addressing"`
+
+	/* Naming, if set, is the naming rules preset for the provider.
+	Names the preset doesn't allow aren't checked. */
+	Naming string `json:"naming"`
+
+	/* Statuses maps the provider's HTTP status codes to what they mean,
+	one of the keys of PROVIDERSTATUSES */
+	Statuses map[string]string `json:"statuses"`
+}
+
+/* loadProviders returns the providers defined in the JSON file named fn,
+which holds an array of provider definitions.  None of the providers may have
+the same name as another or as one of the endpoints in builtin.  If fn is the
+empty string, loadProviders returns nil. */
+func loadProviders(fn string, builtin []endpoints) ([]provider, error) {
+	if "" == fn {
+		return nil, nil
+	}
+	f, err := os.Open(fn)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+	var defs []providerDef
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&defs); nil != err {
+		return nil, err
+	}
+
+	/* Names should be unique, or the stats won't make sense */
+	names := make(map[string]struct{})
+	for _, ep := range builtin {
+		names[ep.name] = struct{}{}
+	}
+
+	ps := make([]provider, 0, len(defs))
+	for i, d := range defs {
+		p, err := d.provider()
+		if nil != err {
+			return nil, fmt.Errorf("provider %v: %v", i+1, err)
+		}
+		if _, ok := names[p.ep.name]; ok {
+			return nil, fmt.Errorf(
+				"provider %v: duplicate name %q",
+				i+1,
+				p.ep.name,
+			)
+		}
+		names[p.ep.name] = struct{}{}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+/* provider returns an s3Provider for d. */
+func (d providerDef) provider() (s3Provider, error) {
+	var p s3Provider
+	if "" == d.Name {
+		return p, fmt.Errorf("missing name")
+	}
+
+	/* Work out how many placeholders the URLs need */
+	var virtual bool
+	switch strings.ToLower(d.Addressing) {
+	case "", "path":
+	case "virtual":
+		virtual = true
+	default:
+		return p, fmt.Errorf(
+			"%v: unknown addressing style %q "+
+				"(known styles: path, virtual)",
+			d.Name,
+			d.Addressing,
+		)
+	}
+	nPH := 0
+	if virtual {
+		nPH = 1
+	}
+	if "" == d.URL {
+		return p, fmt.Errorf("%v: missing url", d.Name)
+	}
+	if nPH != strings.Count(d.URL, "%v") {
+		return p, fmt.Errorf(
+			"%v: need exactly %v %%v in url %q",
+			d.Name,
+			nPH,
+			d.URL,
+		)
+	}
+	if "" != d.RegionalURL &&
+		nPH+1 != strings.Count(d.RegionalURL, "%v") {
+		return p, fmt.Errorf(
+			"%v: need exactly %v %%v in regional_url %q",
+			d.Name,
+			nPH+1,
+			d.RegionalURL,
+		)
+	}
+	p.ep = endpoints{
+		name:     d.Name,
+		global:   d.URL,
+		regional: d.RegionalURL,
+		virtual:  virtual,
+		path:     !virtual,
+		notS3:    true,
+	}
+
+	/* Names the provider won't allow */
+	if "" != d.Naming {
+		r, err := getNamingRules(d.Naming, "", 0)
+		if nil != err {
+			return p, fmt.Errorf("%v: %v", d.Name, err)
+		}
+		p.rules = &r
+	}
+
+	/* Statuses which mean what S3 means by another */
+	if 0 != len(d.Statuses) {
+		p.ep.statuses = make(map[int]int, len(d.Statuses))
+	}
+	for c, m := range d.Statuses {
+		code, err := strconv.Atoi(c)
+		if nil != err || 100 > code || 599 < code {
+			return p, fmt.Errorf(
+				"%v: invalid status code %q",
+				d.Name,
+				c,
+			)
+		}
+		s, ok := PROVIDERSTATUSES[m]
+		if !ok {
+			return p, fmt.Errorf(
+				"%v: unknown meaning %q for status %v "+
+					"(known meanings: %v, %v, %v, %v)",
+				d.Name,
+				m,
+				code,
+				StatusPublic,
+				StatusForbidden,
+				StatusNotBucket,
+				StatusRedirect,
+			)
+		}
+		p.ep.statuses[code] = s
+	}
+
+	return p, nil
+}
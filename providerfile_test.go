@@ -0,0 +1,113 @@
+package main
+
+/*
+ * providerfile_test.go
+ * Tests for providers defined in a file
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProviderDefPathAddressing(t *testing.T) {
+	var (
+		gotHost string
+		gotPath string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		gotHost = r.Host
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if nil != err {
+		t.Fatalf("Parsing server URL %q: %v", srv.URL, err)
+	}
+
+	for _, addr := range []string{"", "path"} {
+		gotHost, gotPath = "", ""
+		p, err := providerDef{
+			Name:       "test",
+			URL:        srv.URL,
+			Addressing: addr,
+		}.provider()
+		if nil != err {
+			t.Fatalf("Addressing %q: provider: %v", addr, err)
+		}
+		p.Check(
+			context.Background(),
+			candidate{name: "bucket", input: "bucket"},
+			0,
+			newTestCheckConfig(srv.Client()),
+		)
+		if u.Host != gotHost {
+			t.Errorf(
+				"Addressing %q: Host %q, want %q",
+				addr,
+				gotHost,
+				u.Host,
+			)
+		}
+		if "/bucket" != gotPath {
+			t.Errorf(
+				"Addressing %q: path %q, want %q",
+				addr,
+				gotPath,
+				"/bucket",
+			)
+		}
+	}
+}
+
+func TestEndpointsURLPath(t *testing.T) {
+	for _, c := range []struct {
+		ep     endpoints
+		region string
+		want   string
+	}{{
+		ep:   endpoints{global: "https://example.com", path: true},
+		want: "https://example.com/b",
+	}, {
+		ep:   endpoints{global: "https://example.com/", path: true},
+		want: "https://example.com/b",
+	}, {
+		ep: endpoints{
+			global:   "https://example.com",
+			regional: "https://%v.example.com",
+			path:     true,
+		},
+		region: "r1",
+		want:   "https://r1.example.com/b",
+	}, {
+		ep: endpoints{
+			global:  "https://%v.example.com",
+			virtual: true,
+			path:    true,
+		},
+		want: "https://b.example.com",
+	}, {
+		ep:   endpoints{global: S3URL},
+		want: S3URL,
+	}} {
+		if got := c.ep.url("b", c.region); c.want != got {
+			t.Errorf(
+				"%+v in %q: got %q, want %q",
+				c.ep,
+				c.region,
+				got,
+				c.want,
+			)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+/*
+ * proxy.go
+ * Send requests through a proxy
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/* PROXYSCHEMES are the proxy URL schemes net/http understands. */
+var PROXYSCHEMES = []string{"http", "https", "socks5", "socks5h"}
+
+/* parseProxy parses and checks the proxy URL s. */
+func parseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if nil != err {
+		return nil, err
+	}
+	ok := false
+	for _, sc := range PROXYSCHEMES {
+		if sc == u.Scheme {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf(
+			"unsupported scheme %q (supported schemes: %v)",
+			u.Scheme,
+			strings.Join(PROXYSCHEMES, ", "),
+		)
+	}
+	if "" == u.Hostname() {
+		return nil, fmt.Errorf("missing host")
+	}
+	return u, nil
+}
+
+/* useProxy sends every HTTP request made with http.DefaultTransport, or a
+clone of it, through the proxy at u.  It must be called before any clones are
+made.  The certificate stream library makes its own connections, but asks the
+environment for a proxy, so the environment is updated as well. */
+func useProxy(u *url.URL) error {
+	http.DefaultTransport.(*http.Transport).Proxy = http.ProxyURL(u)
+	return os.Setenv("HTTPS_PROXY", u.String())
+}
@@ -0,0 +1,161 @@
+package main
+
+/*
+ * psl.go
+ * Public suffix list handling
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+/* suffixList works out the public suffixes of domain names, using either
+the list built into golang.org/x/net/publicsuffix or one loaded from a file,
+plus any extra suffixes. */
+type suffixList struct {
+	/* rules, wildcards, and exceptions are the normal rules, the
+	parents of wildcard (*.) rules, and the exception (!) rules, in the
+	public suffix list format */
+	rules      map[string]struct{}
+	wildcards  map[string]struct{}
+	exceptions map[string]struct{}
+
+	/* builtin causes the built-in list to be used in addition to the
+	rules */
+	builtin bool
+}
+
+/* newSuffixList returns a suffixList which uses the public suffix list in the
+file named fn, or the built-in list if fn is the empty string, plus the
+suffixes in the comma-separated list extra. */
+func newSuffixList(fn, extra string) (*suffixList, error) {
+	l := &suffixList{
+		rules:      make(map[string]struct{}),
+		wildcards:  make(map[string]struct{}),
+		exceptions: make(map[string]struct{}),
+		builtin:    "" == fn,
+	}
+
+	/* Load the list, if we have one */
+	if "" != fn {
+		if err := l.load(fn); nil != err {
+			return nil, err
+		}
+	}
+
+	/* Add in the extra suffixes */
+	for _, s := range strings.Split(extra, ",") {
+		if s = strings.TrimSpace(s); "" != s {
+			l.add(s)
+		}
+	}
+
+	return l, nil
+}
+
+/* load adds the rules from the public suffix list in the file named fn. */
+func (l *suffixList) load(fn string) error {
+	f, err := os.Open(fn)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	var nr int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		/* Rules end at the first whitespace */
+		fs := strings.Fields(scanner.Text())
+		if 0 == len(fs) || strings.HasPrefix(fs[0], "//") {
+			continue
+		}
+		l.add(fs[0])
+		nr++
+	}
+	if err := scanner.Err(); nil != err {
+		return err
+	}
+	if 0 == nr {
+		return fmt.Errorf("no rules found")
+	}
+	return nil
+}
+
+/* add adds the rule r, which may be a wildcard or exception rule. */
+func (l *suffixList) add(r string) {
+	r = strings.Trim(strings.ToLower(r), ".")
+	switch {
+	case strings.HasPrefix(r, "!"):
+		l.exceptions[r[1:]] = struct{}{}
+	case strings.HasPrefix(r, "*."):
+		l.wildcards[r[2:]] = struct{}{}
+	default:
+		l.rules[r] = struct{}{}
+	}
+}
+
+/* PublicSuffix returns the public suffix of the domain name orig, which will
+be a suffix of orig. */
+func (l *suffixList) PublicSuffix(orig string) string {
+	d := strings.ToLower(orig)
+
+	/* Find the longest matching rule, if any */
+	var ps string
+	labels := strings.Split(d, ".")
+	for i := range labels {
+		s := strings.Join(labels[i:], ".")
+		if _, ok := l.exceptions[s]; ok {
+			ps = strings.Join(labels[i+1:], ".")
+			break
+		}
+		if _, ok := l.rules[s]; ok {
+			ps = s
+			break
+		}
+		if i+1 < len(labels) {
+			p := strings.Join(labels[i+1:], ".")
+			if _, ok := l.wildcards[p]; ok {
+				ps = s
+				break
+			}
+		}
+	}
+
+	/* Without a match, the default is the last label, unless the
+	built-in list knows better */
+	def := labels[len(labels)-1]
+	if l.builtin {
+		def, _ = publicsuffix.PublicSuffix(d)
+	}
+	if len(ps) < len(def) {
+		ps = def
+	}
+
+	/* Keep the case we were given */
+	if len(orig) == len(d) {
+		return orig[len(orig)-len(ps):]
+	}
+	return ps
+}
+
+/* EffectiveTLDPlusOne returns the public suffix of the domain name d plus one
+more label, i.e. the registrable domain. */
+func (l *suffixList) EffectiveTLDPlusOne(d string) (string, error) {
+	ps := l.PublicSuffix(d)
+	if len(d) <= len(ps) {
+		return "", fmt.Errorf("%v is a public suffix", d)
+	}
+	i := len(d) - len(ps) - 1
+	if '.' != d[i] {
+		return "", fmt.Errorf("invalid domain %q", d)
+	}
+	return d[1+strings.LastIndex(d[:i], "."):], nil
+}
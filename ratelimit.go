@@ -0,0 +1,222 @@
+package main
+
+/*
+ * ratelimit.go
+ * Don't make requests too quickly
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// ADAPTIVESTART is the number of requests per second an
+	// adaptiveLimiter starts with
+	ADAPTIVESTART = 5
+
+	// ADAPTIVEMIN is the fewest requests per second an adaptiveLimiter
+	// will back off to
+	ADAPTIVEMIN = 0.5
+
+	// ADAPTIVEINCREASE is roughly how many more requests per second an
+	// adaptiveLimiter allows after each second without throttling
+	ADAPTIVEINCREASE = 1
+
+	// ADAPTIVEDECREASE is what an adaptiveLimiter multiplies its rate by
+	// when throttled
+	ADAPTIVEDECREASE = 0.5
+
+	// ADAPTIVECOOLDOWN is how long after backing off an adaptiveLimiter
+	// ignores throttling, which was likely caused by requests made before
+	// it backed off
+	ADAPTIVECOOLDOWN = time.Second
+
+	// ADAPTIVELOGINTERVAL is how often an adaptiveLimiter's rate is
+	// logged, if it's changed
+	ADAPTIVELOGINTERVAL = 30 * time.Second
+)
+
+/* rateLimiter spaces out events so there are no more than a given number
+per second.  A nil *rateLimiter doesn't limit anything.  It is safe to call
+rateLimiter's methods from multiple goroutines. */
+type rateLimiter struct {
+	l        sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+/* newRateLimiter returns a rateLimiter which allows rate events per second,
+or nil if rate isn't positive. */
+func newRateLimiter(rate float64) *rateLimiter {
+	if 0 >= rate {
+		return nil
+	}
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rate),
+	}
+}
+
+/* Wait waits until another event is allowed. */
+func (r *rateLimiter) Wait() { r.WaitContext(context.Background()) }
+
+/* WaitContext waits until another event is allowed, or ctx is done, in which
+case it returns false. */
+func (r *rateLimiter) WaitContext(ctx context.Context) bool {
+	if nil == r {
+		return true
+	}
+	r.l.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	d := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.l.Unlock()
+	if 0 >= d {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+/* SetRate changes the number of events allowed per second to rate, which
+must be positive. */
+func (r *rateLimiter) SetRate(rate float64) {
+	if nil == r {
+		return
+	}
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.interval = time.Duration(float64(time.Second) / rate)
+}
+
+/* waitTurn waits until both l and a allow another request, or ctx is done, in
+which case it returns false.  Either may be nil. */
+func waitTurn(ctx context.Context, l *rateLimiter, a *adaptiveLimiter) bool {
+	return l.WaitContext(ctx) && a.WaitContext(ctx)
+}
+
+/* adaptiveLimiter is a rateLimiter which tunes its own rate, increasing it
+additively while requests aren't throttled and decreasing it multiplicatively
+when they are.  A nil *adaptiveLimiter doesn't limit anything.  It is safe to
+call adaptiveLimiter's methods from multiple goroutines. */
+type adaptiveLimiter struct {
+	rl *rateLimiter
+
+	l       sync.Mutex
+	rate    float64
+	lowest  float64
+	lastCut time.Time
+	cuts    uint
+}
+
+/* newAdaptiveLimiter returns an adaptiveLimiter which starts at
+ADAPTIVESTART requests per second, or nil if enabled is false. */
+func newAdaptiveLimiter(enabled bool) *adaptiveLimiter {
+	if !enabled {
+		return nil
+	}
+	return &adaptiveLimiter{
+		rl:     newRateLimiter(ADAPTIVESTART),
+		rate:   ADAPTIVESTART,
+		lowest: ADAPTIVESTART,
+	}
+}
+
+/* Wait waits until another request is allowed. */
+func (a *adaptiveLimiter) Wait() { a.WaitContext(context.Background()) }
+
+/* WaitContext waits until another request is allowed, or ctx is done, in
+which case it returns false. */
+func (a *adaptiveLimiter) WaitContext(ctx context.Context) bool {
+	if nil == a {
+		return true
+	}
+	return a.rl.WaitContext(ctx)
+}
+
+/* OK notes a request which wasn't throttled, and speeds up a bit. */
+func (a *adaptiveLimiter) OK() {
+	if nil == a {
+		return
+	}
+	a.l.Lock()
+	defer a.l.Unlock()
+	/* A bit more per request works out to about ADAPTIVEINCREASE per
+	second at any rate */
+	a.rate += ADAPTIVEINCREASE / a.rate
+	a.rl.SetRate(a.rate)
+}
+
+/* Throttled notes a throttled request, and slows down, unless it's just
+slowed down. */
+func (a *adaptiveLimiter) Throttled() {
+	if nil == a {
+		return
+	}
+	a.l.Lock()
+	defer a.l.Unlock()
+	if time.Since(a.lastCut) < ADAPTIVECOOLDOWN {
+		return
+	}
+	a.lastCut = time.Now()
+	a.cuts++
+	a.rate *= ADAPTIVEDECREASE
+	if ADAPTIVEMIN > a.rate {
+		a.rate = ADAPTIVEMIN
+	}
+	if a.rate < a.lowest {
+		a.lowest = a.rate
+	}
+	a.rl.SetRate(a.rate)
+	log.Printf("Throttled, slowing to %.2f requests/second", a.rate)
+}
+
+/* Log logs the current rate every ADAPTIVELOGINTERVAL, if it's changed.  It
+never returns. */
+func (a *adaptiveLimiter) Log() {
+	if nil == a {
+		return
+	}
+	var last string
+	for range time.Tick(ADAPTIVELOGINTERVAL) {
+		if s := a.String(); s != last {
+			log.Printf("Adaptive rate: %v", s)
+			last = s
+		}
+	}
+}
+
+/* String returns the current rate, the lowest it's been, and how many times
+it's been decreased. */
+func (a *adaptiveLimiter) String() string {
+	if nil == a {
+		return "none"
+	}
+	a.l.Lock()
+	defer a.l.Unlock()
+	bo := "backed off 1 time"
+	if 1 != a.cuts {
+		bo = fmt.Sprintf("backed off %v times", a.cuts)
+	}
+	return fmt.Sprintf(
+		"%.2f requests/second (lowest %.2f, %v)",
+		a.rate,
+		a.lowest,
+		bo,
+	)
+}
@@ -0,0 +1,132 @@
+package main
+
+/*
+ * ratelimit_test.go
+ * Tests for not sending requests too fast
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/* TestFollowupRequestsWait checks that the requests made after a bucket's
+found wait their turn. */
+func TestFollowupRequestsWait(t *testing.T) {
+	var nReq int32
+	srv := httptest.NewServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		atomic.AddInt32(&nReq, 1)
+	}))
+	defer srv.Close()
+
+	cand := candidate{name: "bucket", input: "bucket"}
+	for _, c := range []struct {
+		name string
+		f    func(orig *http.Request, conf *checkConfig) bool
+	}{{
+		name: "confirmCheck",
+		f: func(orig *http.Request, conf *checkConfig) bool {
+			conf.confirm = srv.Client()
+			return confirmCheck(cand, orig, srv.URL, 200, 0, conf)
+		},
+	}, {
+		name: "checkRequesterPays",
+		f: func(orig *http.Request, conf *checkConfig) bool {
+			conf.creds = &awsCreds{id: "id", secret: "secret"}
+			return checkRequesterPays(
+				cand,
+				orig,
+				srv.URL,
+				"",
+				0,
+				conf,
+			)
+		},
+	}, {
+		name: "prefixReadable",
+		f: func(orig *http.Request, conf *checkConfig) bool {
+			return prefixReadable(cand, orig, "p", 0, conf)
+		},
+	}, {
+		name: "getSubresource",
+		f: func(orig *http.Request, conf *checkConfig) bool {
+			_, ok := getSubresource(cand, orig, "acl", 0, conf)
+			return ok
+		},
+	}, {
+		name: "listBucket",
+		f: func(orig *http.Request, conf *checkConfig) bool {
+			_, _, err := listBucket(
+				conf.client,
+				srv.URL,
+				listLimits{
+					timeout: 50 * time.Millisecond,
+					limiter: conf.limiter,
+				},
+				func(listedObject) {},
+			)
+			return nil != err
+		},
+	}} {
+		atomic.StoreInt32(&nReq, 0)
+
+		/* A limiter whose next turn is a long way off */
+		conf := newTestCheckConfig(srv.Client())
+		conf.limiter = newRateLimiter(0.001)
+		conf.limiter.Wait()
+
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			50*time.Millisecond,
+		)
+		orig, err := http.NewRequestWithContext(
+			ctx,
+			"GET",
+			srv.URL,
+			nil,
+		)
+		if nil != err {
+			cancel()
+			t.Fatalf("Making request: %v", err)
+		}
+		ok := c.f(orig, conf)
+		cancel()
+		if ok {
+			t.Errorf("%v: succeeded without its turn", c.name)
+		}
+		if n := atomic.LoadInt32(&nReq); 0 != n {
+			t.Errorf("%v: made %v requests, want 0", c.name, n)
+		}
+	}
+}
+
+func TestWaitTurn(t *testing.T) {
+	/* No limits means no waiting */
+	if !waitTurn(context.Background(), nil, nil) {
+		t.Errorf("waitTurn with no limits returned false")
+	}
+
+	/* No turn before ctx is done */
+	a := newAdaptiveLimiter(true)
+	a.Wait()
+	a.rl.SetRate(0.001)
+	a.Wait()
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond,
+	)
+	defer cancel()
+	if waitTurn(ctx, nil, a) {
+		t.Errorf("waitTurn returned true before adaptive allowed it")
+	}
+}
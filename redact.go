@@ -0,0 +1,87 @@
+package main
+
+/*
+ * redact.go
+ * Keep secrets out of logs
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+)
+
+// REDACTED replaces secrets in logs
+const REDACTED = "<redacted>"
+
+// SECRETFLAGS are the flags whose values are secrets
+var SECRETFLAGS = map[string]struct{}{
+	"passivedns-key": {},
+}
+
+/* redactedFlag returns the value v of the flag named name with any secrets
+removed, suitable for logging.  The values of SECRETFLAGS are replaced with
+REDACTED and URLs' passwords, such as -proxy's, are masked. */
+func redactedFlag(name, v string) string {
+	if _, ok := SECRETFLAGS[name]; ok && "" != v {
+		return REDACTED
+	}
+	if u, err := url.Parse(v); nil == err && nil != u.User {
+		return u.Redacted()
+	}
+	return v
+}
+
+/* redactedArgs returns a copy of the command-line arguments args, parsed as
+fs parses them, with the values of the flags in fs passed through redactedFlag.
+Arguments after the flags only have URLs' passwords masked. */
+func redactedArgs(fs *flag.FlagSet, args []string) []string {
+	var (
+		rs    = make([]string, len(args))
+		flags = true /* Still parsing flags. */
+		name  string /* Flag whose value is the next argument. */
+	)
+	for i, a := range args {
+		/* Values separate from their flags. */
+		if "" != name {
+			rs[i] = redactedFlag(name, a)
+			name = ""
+			continue
+		}
+
+		/* Flag parsing stops at the first non-flag or --. */
+		if flags && "--" == a {
+			flags = false
+			rs[i] = a
+			continue
+		}
+		if !flags || "-" == a || !strings.HasPrefix(a, "-") {
+			flags = false
+			rs[i] = redactedFlag("", a)
+			continue
+		}
+
+		/* -flag=value */
+		n := strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if k, v, ok := strings.Cut(n, "="); ok {
+			rs[i] = a[:len(a)-len(v)] + redactedFlag(k, v)
+			continue
+		}
+
+		/* -flag value, unless it's a boolean flag */
+		rs[i] = a
+		f := fs.Lookup(n)
+		if nil == f {
+			continue
+		}
+		if bf, ok := f.Value.(interface {
+			IsBoolFlag() bool
+		}); !ok || !bf.IsBoolFlag() {
+			name = n
+		}
+	}
+	return rs
+}
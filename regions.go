@@ -0,0 +1,130 @@
+package main
+
+/*
+ * regions.go
+ * Find out which regions AWS has
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// AWSREGIONSURL is where AWS publishes its IP ranges, which say which
+	// regions have S3
+	AWSREGIONSURL = "https://ip-ranges.amazonaws.com/ip-ranges.json"
+
+	// AWSREGIONSTIMEOUT is how long to wait for AWSREGIONSURL
+	AWSREGIONSTIMEOUT = 30 * time.Second
+
+	// AWSREGIONSMAXBYTES is the maximum number of bytes to read from
+	// AWSREGIONSURL
+	AWSREGIONSMAXBYTES = 64 * 1024 * 1024
+)
+
+/* AWSREGIONS are the regions with S3 in AWS's standard partition, as of the
+time of writing.  They're used when the current list can't be had. */
+var AWSREGIONS = []string{
+	"af-south-1",
+	"ap-east-1",
+	"ap-east-2",
+	"ap-northeast-1",
+	"ap-northeast-2",
+	"ap-northeast-3",
+	"ap-south-1",
+	"ap-south-2",
+	"ap-southeast-1",
+	"ap-southeast-2",
+	"ap-southeast-3",
+	"ap-southeast-4",
+	"ap-southeast-5",
+	"ap-southeast-6",
+	"ap-southeast-7",
+	"ca-central-1",
+	"ca-west-1",
+	"eu-central-1",
+	"eu-central-2",
+	"eu-north-1",
+	"eu-south-1",
+	"eu-south-2",
+	"eu-west-1",
+	"eu-west-2",
+	"eu-west-3",
+	"il-central-1",
+	"me-central-1",
+	"me-south-1",
+	"mx-central-1",
+	"sa-east-1",
+	"us-east-1",
+	"us-east-2",
+	"us-west-1",
+	"us-west-2",
+}
+
+/* allRegions returns the regions with S3, sorted, from AWS's published IP
+ranges.  If they can't be had, an error is logged and AWSREGIONS is returned
+instead. */
+func allRegions() []string {
+	rs, err := fetchRegions(AWSREGIONSURL)
+	if nil != err {
+		elog.Printf(
+			"Unable to get AWS regions from %v, using the "+
+				"built-in list: %v",
+			AWSREGIONSURL,
+			err,
+		)
+		return AWSREGIONS
+	}
+	return rs
+}
+
+/* fetchRegions gets the regions with S3 from the AWS IP ranges at u. */
+func fetchRegions(u string) ([]string, error) {
+	c := &http.Client{Timeout: AWSREGIONSTIMEOUT}
+	res, err := c.Get(u)
+	if nil != err {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if http.StatusOK != res.StatusCode {
+		return nil, fmt.Errorf("unexpected response %v", res.Status)
+	}
+
+	/* Find the regional S3 ranges */
+	var ranges struct {
+		Prefixes []struct {
+			Region  string `json:"region"`
+			Service string `json:"service"`
+		} `json:"prefixes"`
+	}
+	if err := json.NewDecoder(
+		io.LimitReader(res.Body, AWSREGIONSMAXBYTES),
+	).Decode(&ranges); nil != err {
+		return nil, err
+	}
+	m := make(map[string]struct{})
+	for _, p := range ranges.Prefixes {
+		if "S3" != p.Service || "GLOBAL" == p.Region || "" == p.Region {
+			continue
+		}
+		m[p.Region] = struct{}{}
+	}
+	if 0 == len(m) {
+		return nil, fmt.Errorf("no regions with S3")
+	}
+
+	rs := make([]string, 0, len(m))
+	for r := range m {
+		rs = append(rs, r)
+	}
+	sort.Strings(rs)
+	return rs, nil
+}
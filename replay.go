@@ -0,0 +1,125 @@
+package main
+
+/*
+ * replay.go
+ * Replay responses from a trace
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+/* replayTransport is an http.RoundTripper which answers requests with the
+responses recorded in a trace, instead of using the network.  Requests are
+matched by method, URL, and Host.  If the same request was made more than
+once, the recorded responses are returned in order, with the last one
+repeated once they run out. */
+type replayTransport struct {
+	l    sync.Mutex
+	recs map[string][]traceRecord
+}
+
+/* newReplayTransport returns a replayTransport which replays the trace in the
+file named fn, as well as the names checked in the trace, in the order they
+were first checked. */
+func newReplayTransport(fn string) (*replayTransport, []string, error) {
+	f, err := os.Open(fn)
+	if nil != err {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var (
+		rt  = &replayTransport{recs: make(map[string][]traceRecord)}
+		ns  []string
+		sn  = make(map[string]struct{})
+		dec = json.NewDecoder(bufio.NewReader(f))
+	)
+	for dec.More() {
+		var r traceRecord
+		if err := dec.Decode(&r); nil != err {
+			return nil, nil, err
+		}
+		k := replayKey(r.Method, r.URL, r.Host)
+		rt.recs[k] = append(rt.recs[k], r)
+
+		/* Older traces don't have the name, but the Host usually
+		is it */
+		n := r.Name
+		if "" == n {
+			n = r.Host
+		}
+		if "" == n {
+			continue
+		}
+		if _, ok := sn[n]; ok {
+			continue
+		}
+		sn[n] = struct{}{}
+		ns = append(ns, n)
+	}
+	return rt, ns, nil
+}
+
+/* replayKey returns the key used to find a recorded request. */
+func replayKey(method, u, host string) string {
+	return method + " " + u + " " + host
+}
+
+/* RoundTrip returns the recorded response or error for req. */
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	/* Work out which recording to use */
+	t.l.Lock()
+	k := replayKey(req.Method, req.URL.String(), req.Host)
+	rs := t.recs[k]
+	if 0 == len(rs) {
+		t.l.Unlock()
+		return nil, fmt.Errorf("no recorded response")
+	}
+	r := rs[0]
+	if 1 < len(rs) {
+		t.recs[k] = rs[1:]
+	}
+	t.l.Unlock()
+
+	/* Requests which failed fail again, without the client's wrapping
+	from the first time */
+	if "" != r.Error {
+		op := req.Method[:1] + strings.ToLower(req.Method[1:])
+		return nil, errors.New(strings.TrimPrefix(
+			r.Error,
+			fmt.Sprintf("%s %q: ", op, r.URL),
+		))
+	}
+
+	/* Roll the recorded response */
+	res := &http.Response{
+		Status: fmt.Sprintf(
+			"%v %v",
+			r.Status,
+			http.StatusText(r.Status),
+		),
+		StatusCode: r.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(r.Body)),
+		Request:    req,
+	}
+	for h, v := range r.Headers {
+		res.Header.Set(h, v)
+	}
+	return res, nil
+}
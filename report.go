@@ -0,0 +1,205 @@
+package main
+
+/*
+ * report.go
+ * Save findings to an object store
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// REPORTINTERVAL is how often the report is written to its store, if
+	// it's changed
+	REPORTINTERVAL = time.Minute
+
+	// REPORTTIMEOUT is how long to wait for the report to be stored
+	REPORTTIMEOUT = time.Minute
+)
+
+/* objectStore is somewhere a report can be written.  Put replaces the stored
+report with b. */
+type objectStore interface {
+	Put(b []byte) error
+	String() string
+}
+
+/* newObjectStore returns an objectStore for the URL u, which may be of the
+form s3://bucket/key. */
+func newObjectStore(u string) (objectStore, error) {
+	pu, err := url.Parse(u)
+	if nil != err {
+		return nil, err
+	}
+	switch pu.Scheme {
+	case "s3":
+		return newS3Store(pu)
+	default:
+		return nil, fmt.Errorf("unsupported store type %q", pu.Scheme)
+	}
+}
+
+/* s3Store is an objectStore which writes to an S3 object. */
+type s3Store struct {
+	bucket string
+	key    string
+	region string
+	creds  *awsCreds
+	client *http.Client
+}
+
+/* newS3Store returns an s3Store for the S3 object at u, which should be of
+the form s3://bucket/key.  Credentials and the region are taken from the
+standard AWS environment variables. */
+func newS3Store(u *url.URL) (*s3Store, error) {
+	s := &s3Store{
+		bucket: u.Host,
+		key:    strings.TrimPrefix(u.Path, "/"),
+		region: os.Getenv("AWS_REGION"),
+		client: &http.Client{Timeout: REPORTTIMEOUT},
+	}
+	if "" == s.bucket || "" == s.key {
+		return nil, fmt.Errorf("need both a bucket and a key")
+	}
+	if "" == s.region {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if "" == s.region {
+		s.region = "us-east-1"
+	}
+	var err error
+	if s.creds, err = awsCredsFromEnv(); nil != err {
+		return nil, err
+	}
+	return s, nil
+}
+
+/* Put writes b to the S3 object. */
+func (s *s3Store) Put(b []byte) error {
+	req, err := http.NewRequest(
+		"PUT",
+		fmt.Sprintf(
+			"https://%v.s3.%v.amazonaws.com/%v",
+			s.bucket,
+			s.region,
+			s.key,
+		),
+		bytes.NewReader(b),
+	)
+	if nil != err {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	s.creds.sign(req, s.region, b)
+	res, err := s.client.Do(req)
+	if nil != err {
+		return err
+	}
+	defer res.Body.Close()
+	if http.StatusOK != res.StatusCode {
+		rb, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%v: %s", res.Status, bytes.TrimSpace(rb))
+	}
+	return nil
+}
+
+/* String returns the s3:// URL for the object. */
+func (s *s3Store) String() string {
+	return "s3://" + s.bucket + "/" + s.key
+}
+
+/* report accumulates findings written to it and periodically saves them to
+an objectStore.  A nil *report discards everything. */
+type report struct {
+	l       sync.Mutex
+	buf     bytes.Buffer
+	changed bool
+	store   objectStore
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+/* newReport returns a report which saves findings to the store at u, which
+is passed to newObjectStore.  If u is the empty string, newReport returns
+nil. */
+func newReport(u string) (*report, error) {
+	if "" == u {
+		return nil, nil
+	}
+	s, err := newObjectStore(u)
+	if nil != err {
+		return nil, err
+	}
+	r := &report{store: s, done: make(chan struct{})}
+	r.wg.Add(1)
+	go r.saver()
+	return r, nil
+}
+
+/* Write adds b to the report.  It never returns an error. */
+func (r *report) Write(b []byte) (int, error) {
+	if nil == r {
+		return len(b), nil
+	}
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.changed = true
+	return r.buf.Write(b)
+}
+
+/* saver saves the report every REPORTINTERVAL until r.done is closed. */
+func (r *report) saver() {
+	defer r.wg.Done()
+	t := time.NewTicker(REPORTINTERVAL)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.save()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+/* save writes the report to the store if it's changed since the last save. */
+func (r *report) save() {
+	r.l.Lock()
+	if !r.changed {
+		r.l.Unlock()
+		return
+	}
+	b := append([]byte(nil), r.buf.Bytes()...)
+	r.changed = false
+	r.l.Unlock()
+
+	if err := r.store.Put(b); nil != err {
+		elog.Printf("Error saving report to %v: %v", r.store, err)
+		/* Try again next time */
+		r.l.Lock()
+		r.changed = true
+		r.l.Unlock()
+	}
+}
+
+/* Close stops periodic saves and saves the report one last time. */
+func (r *report) Close() {
+	if nil == r {
+		return
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.save()
+}
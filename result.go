@@ -0,0 +1,115 @@
+package main
+
+/*
+ * result.go
+ * Results of bucket checks
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Result statuses
+const (
+	StatusPublic           = "public"
+	StatusRequesterPays    = "requester-pays"
+	StatusForbidden        = "forbidden"
+	StatusNotBucket        = "not-a-bucket"
+	StatusUnexpected       = "unexpected"
+	StatusRegionNotAllowed = "region-not-allowed"
+	StatusRedirect         = "redirect"
+	StatusPublicPrefix     = "public-prefix"
+	StatusSuspended        = "suspended"
+)
+
+// Result describes the outcome of checking a bucket name
+type Result struct {
+	Name           string            `json:"name"`
+	BucketURL      string            `json:"bucket_url"`
+	Status         string            `json:"status"`
+	Severity       string            `json:"severity,omitempty"`
+	HTTPStatus     int               `json:"http_status,omitempty"`
+	Region         string            `json:"region,omitempty"`
+	Time           time.Time         `json:"timestamp"`
+	LatencyMS      float64           `json:"latency_ms,omitempty"`
+	Source         string            `json:"source,omitempty"`
+	Versioning     string            `json:"versioning,omitempty"`
+	LifecycleRules *int              `json:"lifecycle_rules,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	StatusLine     string            `json:"status_line,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	Documents      map[string]string `json:"documents,omitempty"`
+	RedirectURL    string            `json:"redirect_url,omitempty"`
+	RedirectHost   string            `json:"redirect_host,omitempty"`
+	Prefixes       []string          `json:"readable_prefixes,omitempty"`
+	OwnerID        string            `json:"owner_id,omitempty"`
+	OwnerName      string            `json:"owner_name,omitempty"`
+	RunID          string            `json:"run_id,omitempty"`
+	RunStart       *time.Time        `json:"run_start,omitempty"`
+}
+
+/* resultSink is something which wants results.  Send must not block for
+long. */
+type resultSink interface {
+	Send(r Result)
+}
+
+/* headerDump picks which response headers to include in results.  A nil
+*headerDump picks none. */
+type headerDump struct {
+	all   bool
+	names []string
+}
+
+/* newHeaderDump returns a headerDump which picks the headers in the
+comma-separated list s, or all headers if s is "all".  If s is the empty
+string, newHeaderDump returns nil. */
+func newHeaderDump(s string) *headerDump {
+	if "" == s {
+		return nil
+	}
+	if "all" == s {
+		return &headerDump{all: true}
+	}
+	d := &headerDump{}
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); "" != n {
+			d.names = append(d.names, http.CanonicalHeaderKey(n))
+		}
+	}
+	return d
+}
+
+/* Headers returns the headers from h which d picks, with multiple values
+joined with commas.  It returns nil if d is nil or none of the headers are
+in h. */
+func (d *headerDump) Headers(h http.Header) map[string]string {
+	if nil == d {
+		return nil
+	}
+	var m map[string]string
+	add := func(n string, vs []string) {
+		if 0 == len(vs) {
+			return
+		}
+		if nil == m {
+			m = make(map[string]string)
+		}
+		m[n] = strings.Join(vs, ", ")
+	}
+	if d.all {
+		for n, vs := range h {
+			add(n, vs)
+		}
+		return m
+	}
+	for _, n := range d.names {
+		add(n, h[n])
+	}
+	return m
+}
@@ -0,0 +1,70 @@
+package main
+
+/*
+ * runid.go
+ * Tell one run from another
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+/* runInfo identifies a run in results.  A nil *runInfo identifies
+nothing. */
+type runInfo struct {
+	id    string
+	start time.Time
+}
+
+/* newRunInfo returns a runInfo with a new random ID for a run which started
+at start, or nil if enabled is false. */
+func newRunInfo(enabled bool, start time.Time) (*runInfo, error) {
+	if !enabled {
+		return nil, nil
+	}
+	id, err := newUUID()
+	if nil != err {
+		return nil, err
+	}
+	return &runInfo{id: id, start: start.UTC()}, nil
+}
+
+/* Tag adds the run's ID and start time to r. */
+func (ri *runInfo) Tag(r *Result) {
+	if nil == ri {
+		return
+	}
+	r.RunID = ri.id
+	r.RunStart = &ri.start
+}
+
+/* String returns the run's ID. */
+func (ri *runInfo) String() string {
+	if nil == ri {
+		return ""
+	}
+	return ri.id
+}
+
+/* newUUID returns a random (version 4) UUID. */
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); nil != err {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 /* Version 4 */
+	b[8] = b[8]&0x3f | 0x80 /* RFC 4122 variant */
+	return fmt.Sprintf(
+		"%x-%x-%x-%x-%x",
+		b[0:4],
+		b[4:6],
+		b[6:8],
+		b[8:10],
+		b[10:16],
+	), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+/*
+ * s3error.go
+ * Parse S3's error documents
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// S3ERRORMAXBODY is the maximum number of bytes of an error document to read
+const S3ERRORMAXBODY = 64 * 1024
+
+/* s3Error is the error document S3 returns.  Depending on the error, it may
+include the bucket name or the region in which the bucket really is. */
+type s3Error struct {
+	Code       string
+	Message    string
+	BucketName string
+	Region     string
+}
+
+/* readS3Error reads an error document from r.  If r doesn't contain an
+error document, the returned s3Error's fields will be empty. */
+func readS3Error(r io.Reader) s3Error {
+	var e s3Error
+	/* Errors just mean we don't have an error document */
+	xml.NewDecoder(io.LimitReader(r, S3ERRORMAXBODY)).Decode(&e)
+	return e
+}
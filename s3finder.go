@@ -6,27 +6,34 @@ package main
  * Find s3 buckets
  * By J. Stuart McMurray
  * Created 20171202
- * Last Modified 20171224
+ * Last Modified 20261014
  */
 
 import (
 	"bufio"
-	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	certstream "github.com/CaliDog/certstream-go"
-	lru "github.com/hashicorp/golang-lru"
-	"golang.org/x/net/publicsuffix"
+	"github.com/jmoiron/jsonq"
 )
 
 const (
@@ -36,12 +43,31 @@ const (
 	// we're checking it.
 	MAXRECURSION = 10
 
-	// S3URL is the base S3 URL to try, with a placeholder for the region
-	// and the bucket name.
-	S3URL = `https://s3%v.amazonaws.com`
+	// S3URL is the base S3 URL to try when we don't know the bucket's
+	// region.
+	S3URL = `https://s3.amazonaws.com`
 
-	// CTLURL is the URL pattern for querying crt.sh
-	CTLURL = "https://crt.sh/?q=%%.%v&output=json"
+	// CTLURL is the URL for querying crt.sh, less the query string
+	CTLURL = "https://crt.sh/?"
+
+	// CTLQUERY is the default crt.sh query string, which finds
+	// subdomains, with a placeholder for the domain
+	CTLQUERY = "q=%%.%v&output=json"
+
+	// CTLRATE is the default maximum number of crt.sh queries per second
+	CTLRATE = 1
+
+	// CTLMAXLEVELS is the default maximum number of levels of a domain
+	// for which to query crt.sh
+	CTLMAXLEVELS = 1
+
+	// GRPCRATE is the default maximum number of names from gRPC clients
+	// to check per second
+	GRPCRATE = 10
+
+	// CTLMAXBYTES is the default maximum number of bytes to read from a
+	// single crt.sh response
+	CTLMAXBYTES = 64 * 1024 * 1024
 
 	// SEENCACHESIZE is the number of entries in the LRU cache to keep, to
 	// prevent duplicate searches for domains with similar parent domains.
@@ -51,14 +77,32 @@ const (
 	NAMECHARS = "abcdefghijklmnopqrstuvwxyz0123456789-."
 
 	// MAXLABELLEN is the maximum length of a bucket label
-	MAXLABELLEN = 64
+	MAXLABELLEN = 63
+
+	// MAXNAMELEN is the maximum length of an entire bucket name
+	MAXNAMELEN = 63
 
 	// S3PATHURL is the S3 URL with S3 as a path component.  We see this
 	// sometimes as a redirect target.
 	S3PATHURL = "https://aws.amazon.com/s3/"
 
-	// RETRYWAIT is the pause before retries after EOF or no route to host
+	// RETRYWAIT is the pause before retries after EOF, no route to host,
+	// timeouts, and the like
 	RETRYWAIT = time.Second
+
+	// REQUESTTIMEOUT is the default time to wait for a single request
+	REQUESTTIMEOUT = 10 * time.Second
+
+	// SHUFFLEMAX is the maximum number of names to buffer for shuffling
+	SHUFFLEMAX = 1024 * 1024
+
+	// FOLLOWWAIT is how long to wait before checking for more names when
+	// following a file
+	FOLLOWWAIT = time.Second
+
+	// FAILEXITCODE is the exit code used when -fail-on's statuses were
+	// found
+	FAILEXITCODE = 3
 )
 
 func main() {
@@ -66,7 +110,28 @@ func main() {
 		nQuery = flag.Uint(
 			"n",
 			16,
-			"Query at most `N` domains in parallel",
+			"Query at most `N` domains in parallel; this limits "+
+				"requests in total, not per set of endpoints",
+		)
+		ramp = flag.Duration(
+			"ramp",
+			0,
+			"Start the -n checkers gradually, spread over "+
+				"`duration`, to avoid an opening burst of "+
+				"requests",
+		)
+		checkRate = flag.Float64(
+			"rate",
+			0,
+			"Make no more than `N` requests per second checking "+
+				"buckets, across all checkers (0 for no "+
+				"limit)",
+		)
+		adaptiveRate = flag.Bool(
+			"adaptive-rate",
+			false,
+			"Start checking buckets slowly and speed up until S3 "+
+				"responds with 503s, then back off and retry",
 		)
 		nameF = flag.String(
 			"f",
@@ -79,11 +144,72 @@ func main() {
 			false,
 			"Watch certificate transparency logs for names",
 		)
+		dedupWindowLen = flag.Duration(
+			"dedup-window",
+			0,
+			"If set, don't check or report a bucket again for "+
+				"`duration` after it's found",
+		)
+		certIssuer = flag.String(
+			"cert-issuer",
+			"",
+			"If set, with -certs, only check names from "+
+				"certificates with an issuer field containing "+
+				"`issuer` (e.g. Let's Encrypt)",
+		)
 		nonBuckets = flag.Bool(
 			"non-buckets",
 			false,
 			"Print names which don't have an S3 bucket",
 		)
+		company = flag.String(
+			"company",
+			"",
+			"Check names companies commonly use for buckets, "+
+				"generated from the company `name`",
+		)
+		companyProducts = flag.String(
+			"company-products",
+			"",
+			"Comma-separated `list` of the -company's products, "+
+				"for product-specific names",
+		)
+		useAllRegions = flag.Bool(
+			"all-regions",
+			false,
+			"Get the current list of AWS regions with S3, for "+
+				"-company names and to check -allowed-regions",
+		)
+		zoneFile = flag.String(
+			"zonefile",
+			"",
+			"Name of BIND-format zone `file`, e.g. from an "+
+				"AXFR, from which to take the names of "+
+				"A, AAAA, and CNAME records",
+		)
+		certFile = flag.String(
+			"cert-file",
+			"",
+			"Name of `file` with PEM certificates or a CSV of "+
+				"names from certificates, from which to "+
+				"take names as with -certs",
+		)
+		shuffle = flag.Bool(
+			"shuffle",
+			false,
+			fmt.Sprintf(
+				"Check names from the command line and -f in "+
+					"a random order (buffers up to %v "+
+					"names in memory at once)",
+				SHUFFLEMAX,
+			),
+		)
+		follow = flag.Bool(
+			"follow",
+			false,
+			"Keep reading names appended to the file given with "+
+				"-f, like tail -f, until interrupted",
+		)
 		tagFile = flag.String(
 			"tags",
 			"",
@@ -91,23 +217,578 @@ func main() {
 				"the built-in tags, or \"no\" to disable "+
 				"tags altogether",
 		)
+		priorityTagFile = flag.String(
+			"priority-tags",
+			"",
+			"If set, try tags from the file named `F` before "+
+				"other tags",
+		)
 		ignoreNotAllowed = flag.Bool(
 			"ignore-forbidden",
 			false,
 			"Don't print a message when access to a bucket is "+
-				"forbidden (HTTP 403)",
+				"forbidden (HTTP 403); same as -ignore "+
+				"forbidden",
+		)
+		ignoreList = flag.String(
+			"ignore",
+			"",
+			"Don't print messages with the comma-separated "+
+				"`classifications` (forbidden, not-bucket, "+
+				"error, bad-request, or exhausted)",
 		)
 		tryWWW = flag.Bool(
 			"try-www",
 			false,
 			"Don't ignore \"www\" when trying partial names",
 		)
+		withWWW = flag.Bool(
+			"with-www",
+			false,
+			"Also try a \"www.\"-prefixed version of each "+
+				"domain name",
+		)
+		requesterPays = flag.Bool(
+			"check-requester-pays",
+			false,
+			"Retry forbidden (HTTP 403) buckets as requester-pays "+
+				"(requires AWS credentials in the "+
+				"environment, may incur costs)",
+		)
+		checkVersioning = flag.Bool(
+			"check-versioning",
+			false,
+			"Try to read the versioning and lifecycle settings of "+
+				"public buckets",
+		)
+		interact = flag.Bool(
+			"interactive",
+			false,
+			"List public buckets on the terminal as they're "+
+				"found and list their contents on request",
+		)
+		listKeys = flag.Bool(
+			"list",
+			false,
+			"List the keys and sizes of the objects in public "+
+				"buckets as they're found",
+		)
+		maxKeys = flag.Int(
+			"max-keys-per-bucket",
+			10000,
+			"Stop listing a bucket after `N` keys (0 for no limit)",
+		)
+		listTimeout = flag.Duration(
+			"list-timeout",
+			time.Minute,
+			"Stop listing a bucket after `duration` (0 for no "+
+				"limit)",
+		)
+		learnTags = flag.String(
+			"learn-tags",
+			"",
+			"Write the words in found buckets' names to `file`, "+
+				"most common first, for use with -tags",
+		)
+		followRedirects = flag.Bool(
+			"follow-redirects",
+			false,
+			"Report redirects to hosts other than S3, e.g. from "+
+				"bucket website configurations, with "+
+				"where they go",
+		)
+		otlpEndpoint = flag.String(
+			"otlp-endpoint",
+			"",
+			"Export OpenTelemetry traces via OTLP/HTTP to the "+
+				"collector at `URL`, e.g. "+
+				"http://localhost:4318/v1/traces",
+		)
+		groupOwners = flag.Bool(
+			"group-owners",
+			false,
+			"At the end of the run, list the buckets found by "+
+				"owner, from listings and readable ACLs "+
+				"(implies -check-policy)",
+		)
+		confirm = flag.Bool(
+			"confirm",
+			false,
+			"Check public and forbidden buckets a second time, "+
+				"on a new connection, and only report them "+
+				"if both checks agree",
+		)
+		headForRegion = flag.Bool(
+			"head-region",
+			false,
+			"Find each bucket's region with a HEAD request "+
+				"before checking it, rather than following "+
+				"redirects",
+		)
+		prefixFile = flag.String(
+			"prefixes",
+			"",
+			"Name of `file` with key prefixes, one per line, "+
+				"under which to try to list buckets which "+
+				"can't be listed as a whole",
+		)
+		checkPolicy = flag.Bool(
+			"check-policy",
+			false,
+			"Try to read the policy, ACL, and CORS configuration "+
+				"of public and forbidden buckets",
+		)
+		withDates = flag.Bool(
+			"date-suffixes",
+			false,
+			"Also check each name with years and year-months "+
+				"appended, e.g. name-2025 and name202501 "+
+				"(see -date-from and -date-to)",
+		)
+		dateFrom = flag.String(
+			"date-from",
+			"",
+			"With -date-suffixes, start at `YYYY[-MM]` "+
+				"(default January of last year)",
+		)
+		dateTo = flag.String(
+			"date-to",
+			"",
+			"With -date-suffixes, end at `YYYY[-MM]` "+
+				"(default this month)",
+		)
+		bloom = flag.Bool(
+			"bloom",
+			false,
+			"Remember names already checked in a Bloom filter "+
+				"instead of a cache, for long runs; a few "+
+				"unchecked names may be skipped "+
+				"(see -bloom-fp-rate)",
+		)
+		bloomFP = flag.Float64(
+			"bloom-fp-rate",
+			BLOOMFPRATE,
+			"With -bloom, skip at most this `fraction` of "+
+				"unchecked names",
+		)
+		keepOriginal = flag.Bool(
+			"keep-original",
+			false,
+			"Always check each input name, even if it's been "+
+				"checked already, and log how it was "+
+				"normalized (see -validate-only)",
+		)
+		raw = flag.Bool(
+			"raw",
+			false,
+			"Check names exactly as given, without adding tags, "+
+				"splitting domains, or skipping duplicates",
+		)
+		useCloudFront = flag.Bool(
+			"cloudfront",
+			false,
+			"Check if domain names point to CloudFront "+
+				"distributions with S3 origins, and try the "+
+				"bucket directly, if possible",
+		)
+		scrape = flag.Bool(
+			"scrape",
+			false,
+			"Look for buckets and subdomains referenced in "+
+				"domains' robots.txt and sitemap.xml",
+		)
+		maxNames = flag.Uint(
+			"max-names",
+			0,
+			"If nonzero, stop after processing `N` distinct names",
+		)
+		regionURL = flag.String(
+			"region-endpoint",
+			REGIONURL,
+			"Regional S3 URL `template`, with a %v for the "+
+				"region; regions in the China partition use "+
+				"amazonaws.com.cn unless this is changed",
+		)
+		startRegion = flag.String(
+			"start-region",
+			"",
+			"If set, start checking names in the `region`, "+
+				"e.g. cn-north-1 or us-gov-west-1, instead "+
+				"of at S3's global endpoint, which only "+
+				"knows about the standard partition",
+		)
+		endpointURL = flag.String(
+			"endpoint",
+			"",
+			"If set, check names against the S3-compatible "+
+				"endpoint at the URL `template` instead of "+
+				"S3, with a %v for the region if the "+
+				"endpoint's host depends on it",
+		)
+		endpointRegion = flag.String(
+			"endpoint-region",
+			ENDPOINTREGION,
+			"The `region` to use with -endpoint before a "+
+				"bucket's region is known",
+		)
+		allowedRegions = flag.String(
+			"allowed-regions",
+			"",
+			"If set, only follow buckets to the comma-separated "+
+				"`regions`; buckets in other regions are "+
+				"reported but not checked",
+		)
+		dualstack = flag.Bool(
+			"dualstack",
+			false,
+			"Also check each name against S3's dualstack "+
+				"endpoints",
+		)
+		accelerate = flag.Bool(
+			"accelerate",
+			false,
+			"Also check each name against S3's Transfer "+
+				"Acceleration endpoint",
+		)
+		accountID = flag.String(
+			"account-id",
+			"",
+			"Also check each name as an S3 Access Point and "+
+				"Object Lambda Access Point of the AWS "+
+				"account with the given `ID`",
+		)
+		providerFile = flag.String(
+			"providers",
+			"",
+			"Also check each name against the S3-compatible "+
+				"providers defined in the JSON `file`",
+		)
 		useCTL = flag.Bool(
 			"ctl",
 			false,
 			"Query the certificate transparency log database at "+
 				"crt.sh for additional subdomains",
 		)
+		ctlQuery = flag.String(
+			"ctl-query",
+			CTLQUERY,
+			"crt.sh query string `template`, with a %v for the "+
+				"domain",
+		)
+		ctlRate = flag.Float64(
+			"ctl-rate",
+			CTLRATE,
+			"Query crt.sh at most `N` times per second (0 for no "+
+				"limit)",
+		)
+		ctlMaxLevels = flag.Uint(
+			"ctl-max-levels",
+			CTLMAXLEVELS,
+			"Query crt.sh for at most the `N` most-specific "+
+				"levels of a domain, down to its registrable "+
+				"domain (0 for only the registrable domain)",
+		)
+		ctlMaxBytes = flag.Int64(
+			"ctl-max-bytes",
+			CTLMAXBYTES,
+			"Read at most `N` bytes of each crt.sh response",
+		)
+		ctlStateFile = flag.String(
+			"ctl-state",
+			"",
+			"If set, remember crt.sh's cache validators and "+
+				"subdomains in the file named `F` between "+
+				"runs, and don't fetch unchanged results "+
+				"again",
+		)
+		usePassiveDNS = flag.Bool(
+			"passivedns",
+			false,
+			"Query SecurityTrails' passive DNS data for "+
+				"additional subdomains",
+		)
+		passiveDNSKey = flag.String(
+			"passivedns-key",
+			"",
+			"SecurityTrails API `key` for -passivedns, instead "+
+				"of $SECURITYTRAILS_API_KEY",
+		)
+		subdomainsFile = flag.String(
+			"subdomains-file",
+			"",
+			"If set, write the unique subdomains found with -ctl "+
+				"or -passivedns to the file named `F`",
+		)
+		negativesFile = flag.String(
+			"negatives-file",
+			"",
+			"If set, write the unique names which were checked "+
+				"and aren't buckets to the file named `F`",
+		)
+		naming = flag.String(
+			"naming",
+			"aws",
+			"Use the bucket naming rules for `provider` (aws, "+
+				"gcs, or azure) when generating names",
+		)
+		nameChars = flag.String(
+			"name-chars",
+			"",
+			"If set, allow only the `characters` in generated "+
+				"names instead of those allowed by -naming",
+		)
+		maxLabelLen = flag.Int(
+			"max-label-len",
+			0,
+			"If nonzero, skip names with a dot-separated label "+
+				"longer than `N` instead of using -naming's "+
+				"limit",
+		)
+		pslFile = flag.String(
+			"psl",
+			"",
+			"If set, use the public suffix list in the file "+
+				"named `F` instead of the built-in list",
+		)
+		privateSuffixes = flag.String(
+			"private-suffixes",
+			"",
+			"Comma-separated extra public `suffixes` (e.g. "+
+				"internal.corp), under which names won't be "+
+				"split any further",
+		)
+		domainsFile = flag.String(
+			"domains-file",
+			"",
+			"If set, write the unique registrable domains seen to "+
+				"the file named `F`",
+		)
+		finalFile = flag.String(
+			"report",
+			"",
+			"If set, write a sorted, deduplicated report of "+
+				"findings as JSON lines to the file named "+
+				"`F` at the end of the run",
+		)
+		withRunID = flag.Bool(
+			"run-id",
+			false,
+			"Generate a unique ID for this run, and include it "+
+				"and the run's start time in JSON and "+
+				"SARIF output",
+		)
+		sarifFile = flag.String(
+			"sarif",
+			"",
+			"If set, write findings as SARIF to the file named "+
+				"`F` at the end of the run",
+		)
+		reportURL = flag.String(
+			"report-s3",
+			"",
+			"If set, periodically save found buckets to the S3 "+
+				"object at `URL`, of the form "+
+				"s3://bucket/key (requires AWS credentials "+
+				"in the environment)",
+		)
+		skipKnown = flag.String(
+			"skip-known",
+			"",
+			"Don't recheck public buckets listed in the `file` "+
+				"of JSON results (e.g. from -socket) or "+
+				"bucket names from a previous run",
+		)
+		validateOnly = flag.Bool(
+			"validate-only",
+			false,
+			"Report whether each name from the command line and "+
+				"-f is a valid bucket name under -naming, "+
+				"and what it'd be normalized to, instead of "+
+				"checking anything",
+		)
+		firstHit = flag.Bool(
+			"first-hit",
+			false,
+			"Stop checking names generated from an input name "+
+				"once one is found to be a public bucket",
+		)
+		candidatesOnly = flag.Bool(
+			"candidates-only",
+			false,
+			"Print every unique possible bucket name instead of "+
+				"checking them",
+		)
+		estimate = flag.Bool(
+			"estimate",
+			false,
+			"Count the unique possible bucket names and the "+
+				"requests needed to check them instead of "+
+				"checking them",
+		)
+		socketPath = flag.String(
+			"socket",
+			"",
+			"If set, stream results as JSON lines to clients "+
+				"connected to a Unix socket at `path`",
+		)
+		webAddr = flag.String(
+			"web",
+			"",
+			"If set, serve a dashboard showing the run's progress "+
+				"and recent findings on `address`",
+		)
+		grpcAddr = flag.String(
+			"grpc",
+			"",
+			"If set, check names streamed by gRPC clients "+
+				"connecting to `address`, e.g. :50051, and "+
+				"keep running until interrupted",
+		)
+		grpcRate = flag.Float64(
+			"grpc-rate",
+			GRPCRATE,
+			"Check at most `N` names per second from gRPC "+
+				"clients, in total, or 0 for no limit",
+		)
+		showSource = flag.Bool(
+			"show-source",
+			false,
+			"Include how each possible bucket name was generated "+
+				"in output",
+		)
+		dumpHeaders = flag.String(
+			"dump-headers",
+			"",
+			"If set, include the comma-separated response "+
+				"`headers`, or all headers if \"all\", in "+
+				"JSON results for buckets which exist",
+		)
+		reportUnexpected = flag.Bool(
+			"report-unexpected",
+			false,
+			"Report responses other than those S3 normally "+
+				"sends, as though they were found buckets",
+		)
+		timing = flag.Bool(
+			"timing",
+			false,
+			"Include how long requests took in output and print "+
+				"a latency summary at the end",
+		)
+		minTLS = flag.String(
+			"min-tls",
+			"",
+			"If set, require at least TLS `version` 1.0, 1.1, "+
+				"1.2, or 1.3 when checking buckets",
+		)
+		tlsCiphers = flag.String(
+			"tls-ciphers",
+			"",
+			"If set, use only the comma-separated TLS cipher "+
+				"`suites` (e.g. TLS_RSA_WITH_AES_128_CBC_SHA) "+
+				"before TLS 1.3 when checking buckets",
+		)
+		replayFile = flag.String(
+			"replay",
+			"",
+			"If set, check the names in the trace file `F` from "+
+				"-trace using the recorded responses instead "+
+				"of the network",
+		)
+		failOn = flag.String(
+			"fail-on",
+			"none",
+			fmt.Sprintf(
+				"Exit with status %v if any buckets with the "+
+					"comma-separated `statuses` (public, "+
+					"requester-pays, forbidden, "+
+					"public-prefix, suspended, or "+
+					"none) or at "+
+					"least the given severity were found",
+				FAILEXITCODE,
+			),
+		)
+		minSeverity = flag.String(
+			"min-severity",
+			"",
+			"If set, only send results with at least the given "+
+				"`severity` (info, low, medium, high, or "+
+				"critical) to JSON and SARIF output and "+
+				"-fail-on",
+		)
+		http1 = flag.Bool(
+			"http1",
+			false,
+			"Use only HTTP/1.1 when checking buckets, instead of "+
+				"HTTP/2 when S3 allows it",
+		)
+		reqTimeout = flag.Duration(
+			"timeout",
+			REQUESTTIMEOUT,
+			"Give up on a request after `duration` and try "+
+				"again (0 to wait forever)",
+		)
+		userAgent = flag.String(
+			"ua",
+			USERAGENT,
+			"User-Agent `header` to send with requests, or the "+
+				"empty string for none",
+		)
+		proxyURL = flag.String(
+			"proxy",
+			"",
+			"If set, send requests through the proxy at `URL` "+
+				"(http://, https://, or socks5://)",
+		)
+		bindIPs = flag.String(
+			"bind-ips",
+			"",
+			"If set, spread connections for bucket checks across "+
+				"the comma-separated local `addresses`",
+		)
+		showConfig = flag.Bool(
+			"show-config",
+			false,
+			"Log the configuration in use at startup",
+		)
+		outFile = flag.String(
+			"o",
+			"",
+			"If set, append found buckets to the file named `F` "+
+				"instead of writing them to stdout",
+		)
+		publicFile = flag.String(
+			"public-file",
+			"",
+			"If set, also append the URLs of public buckets to "+
+				"the file named `F`, one per line",
+		)
+		jsonOut = flag.Bool(
+			"json",
+			false,
+			"Write results as JSON lines where found buckets "+
+				"would go, and found buckets where "+
+				"informational messages go",
+		)
+		infoFile = flag.String(
+			"info-file",
+			"",
+			"If set, append informational messages to the file "+
+				"named `F` instead of writing them to stderr",
+		)
+		errorFile = flag.String(
+			"error-file",
+			"",
+			"If set, append error messages to the file named `F` "+
+				"instead of writing them to stderr",
+		)
+		traceFile = flag.String(
+			"trace",
+			"",
+			"If set, write a JSON line describing every bucket "+
+				"check request and response to the file "+
+				"named `F`",
+		)
 	)
 	flag.Usage = func() {
 		fmt.Fprintf(
@@ -121,10 +802,21 @@ will be searched, then broken into components and searched.
 Names may be read from a file with -f, in which case blank lines and lines
 starting with a # will be skipped.  The file name may be - to read from stdin.
 
+Names from the command line, a file, and the certificate transparency logs are
+all processed at the same time.  With -certs, s3finder runs until the
+certificate stream ends, otherwise it exits when all of the names have been
+checked.
+
+By default, the label "www" isn't tried on its own, as it's in most domain names
+and would otherwise generate a lot of unrelated buckets; -try-www allows it.
+Independently, -with-www causes www.example.com to be tried as well as
+example.com, for every domain name given.
+
 Tags (such as "backup" and "images" can be added to the names automatically
 with the -tags option.  By default, a built-in list of tags is used.  A custom
 list may be specified as a file with one tag per line.  Blank lines and lines
-starting with a # will be skipped.
+starting with a # will be skipped.  Tags starting with a ! will be tried before
+the others, as will tags in a file given with -priority-tags.
 
 Options:
 `,
@@ -133,12 +825,92 @@ Options:
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	start := time.Now()
+
+	/* Work out where output goes */
+	findings, err := openStream(*outFile, os.Stdout)
+	if nil != err {
+		log.Fatalf("Unable to open output file %v: %v", *outFile, err)
+	}
+	info, err := openStream(*infoFile, os.Stderr)
+	if nil != err {
+		log.Fatalf("Unable to open info file %v: %v", *infoFile, err)
+	}
+	log.SetOutput(info)
+	errs, err := openStream(*errorFile, os.Stderr)
+	if nil != err {
+		log.Fatalf("Unable to open error file %v: %v", *errorFile, err)
+	}
+	elog.SetOutput(errs)
+
+	/* Send everything through a proxy, if asked, before anything's
+	sent anywhere */
+	if "" != *proxyURL {
+		pu, err := parseProxy(*proxyURL)
+		if nil != err {
+			log.Fatalf("Invalid -proxy: %v", err)
+		}
+		if err := useProxy(pu); nil != err {
+			log.Fatalf("Unable to use proxy: %v", err)
+		}
+		log.Printf("Will send requests through %v", pu.Redacted())
+	}
+
+	/* Tell this run's results from everybody else's */
+	run, err := newRunInfo(*withRunID, start)
+	if nil != err {
+		log.Fatalf("Unable to generate run ID: %v", err)
+	}
+	if nil != run {
+		log.Printf("Run ID: %v", run)
+	}
+
+	/* Report of successes, for saving elsewhere */
+	rep, err := newReport(*reportURL)
+	if nil != err {
+		log.Fatalf("Unable to set up report to %v: %v", *reportURL, err)
+	}
+
+	/* Log for successes, which makes way for JSON if need be */
+	var sw io.Writer = findings
+	if *jsonOut {
+		sw = info
+	}
+	if nil != rep {
+		sw = io.MultiWriter(sw, rep)
+	}
+	slog := log.New(sw, "", log.LstdFlags)
+	jl := newJSONLines(findings, *jsonOut)
+
+	/* TLS settings, for picky endpoints */
+	tlsConf, err := newTLSConfig(*minTLS, *tlsCiphers)
+	if nil != err {
+		log.Fatalf("Invalid TLS settings: %v", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	if *http1 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(
+			string,
+			*tls.Conn,
+		) http.RoundTripper)
+	}
 
-	/* Log for successes */
-	slog := log.New(os.Stdout, "", log.LstdFlags)
+	/* Use more than one source address, if we have them */
+	bd, err := newBindDialer(*bindIPs)
+	if nil != err {
+		log.Fatalf("Invalid -bind-ips: %v", err)
+	}
+	if nil != bd {
+		transport.DialContext = bd.DialContext
+		log.Printf("Will make connections from %v", bd)
+	}
 
 	/* HTTP Client which follows no redirects */
 	NRClient := &http.Client{
+		Transport: transport,
+		Timeout:   *reqTimeout,
 		CheckRedirect: func(
 			req *http.Request,
 			via []*http.Request,
@@ -151,89 +923,954 @@ Options:
 		},
 	}
 
+	/* Client for confirming findings, which doesn't reuse connections
+	which might be having a bad day */
+	var confirmClient *http.Client
+	if *confirm {
+		ct := transport.Clone()
+		ct.DisableKeepAlives = true
+		confirmClient = &http.Client{
+			Transport:     ct,
+			Timeout:       NRClient.Timeout,
+			CheckRedirect: NRClient.CheckRedirect,
+		}
+	}
+
+	/* Replay a trace instead of using the network, if asked */
+	var replayNames []string
+	if "" != *replayFile {
+		if 0 != flag.NArg() ||
+			"" != *nameF ||
+			"" != *company ||
+			"" != *zoneFile ||
+			*watchCerts {
+			log.Fatalf(
+				"Names come only from the trace with -replay",
+			)
+		}
+		var rt *replayTransport
+		rt, replayNames, err = newReplayTransport(*replayFile)
+		if nil != err {
+			log.Fatalf(
+				"Unable to read trace %v: %v",
+				*replayFile,
+				err,
+			)
+		}
+		NRClient.Transport = rt
+		if nil != confirmClient {
+			confirmClient.Transport = rt
+		}
+		log.Printf(
+			"Replaying %v names from %v",
+			len(replayNames),
+			*replayFile,
+		)
+	}
+
+	/* Say who we are, or nothing at all.  Clients without a transport
+	of their own, including http.DefaultClient, use
+	http.DefaultTransport. */
+	NRClient.Transport = userAgentTransport{NRClient.Transport, *userAgent}
+	if nil != confirmClient {
+		confirmClient.Transport = userAgentTransport{
+			confirmClient.Transport,
+			*userAgent,
+		}
+	}
+	http.DefaultTransport = userAgentTransport{
+		http.DefaultTransport,
+		*userAgent,
+	}
+
 	/* Get tags */
 	tags, err := getTags(*tagFile)
 	if nil != err {
 		log.Fatalf("Unable to get tags from %v: %v", *tagFile, err)
 	}
+	if "" != *priorityTagFile {
+		ptags, err := getTags(*priorityTagFile)
+		if nil != err {
+			log.Fatalf(
+				"Unable to get priority tags from %v: %v",
+				*priorityTagFile,
+				err,
+			)
+		}
+		tags = prioritizeTags(ptags, tags)
+	}
 	if 1 == len(tags) {
 		log.Printf("Will apply 1 tag to each name")
 	} else {
 		log.Printf("Will apply %v tags to each name", len(tags))
 	}
 
-	/* Cache to prevent duplicate checks */
-	seen, err := lru.New(SEENCACHESIZE)
+	/* Work out which names are allowed */
+	rules, err := getNamingRules(*naming, *nameChars, *maxLabelLen)
 	if nil != err {
-		log.Fatalf("Unable to make seen name cache: %v", err)
-	}
-	if !*tryWWW {
-		seen.Add("www", nil)
+		log.Fatalf("Unable to get naming rules: %v", err)
 	}
 
-	/* Start name processor */
-	var (
-		bucketch = make(chan string)
-		namech   = make(chan string)
-	)
+	/* If we're just validating names, do that and go home */
+	if *validateOnly {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for _, n := range argNames() {
+				ch <- n
+			}
+			if "" == *nameF {
+				return
+			}
+			if err := namesFromFile(ch, *nameF, nil); nil != err {
+				elog.Fatalf(
+					"Error reading names from %v: %v",
+					*nameF,
+					err,
+				)
+			}
+		}()
+		nv, nn, nr, err := validateNames(findings, ch, rules)
+		if nil != err {
+			log.Fatalf("Error writing validation results: %v", err)
+		}
+		log.Printf(
+			"Valid: %v, normalized: %v, rejected: %v",
+			nv,
+			nn,
+			nr,
+		)
+		return
+	}
+
+	/* Work out where to send requests */
+	ep, err := newEndpoints(*regionURL)
+	if nil != err {
+		log.Fatalf("Invalid regional endpoint: %v", err)
+	}
+	if "" != *endpointURL {
+		if REGIONURL != *regionURL {
+			log.Fatalf(
+				"Can't use both -endpoint and " +
+					"-region-endpoint",
+			)
+		}
+		ep, err = newCompatibleEndpoints(
+			*endpointURL,
+			*endpointRegion,
+		)
+		if nil != err {
+			log.Fatalf("Invalid -endpoint: %v", err)
+		}
+	}
+	if "" != *startRegion {
+		if "" != *endpointURL {
+			log.Fatalf("Can't use both -endpoint and -start-region")
+		}
+		if ars := regionSet(*allowedRegions); nil != ars {
+			if _, ok := ars[*startRegion]; !ok {
+				log.Fatalf(
+					"Start region %v isn't allowed",
+					*startRegion,
+				)
+			}
+		}
+		ep.start = *startRegion
+	}
+	eps := []endpoints{ep}
+	if *dualstack {
+		eps = append(eps, DUALSTACKENDPOINTS)
+	}
+	if *accelerate {
+		eps = append(eps, ACCELERATEENDPOINTS)
+	}
+	if "" != *accountID {
+		aeps, err := newAccessPointEndpoints(*accountID)
+		if nil != err {
+			log.Fatalf("Invalid -account-id: %v", err)
+		}
+		eps = append(eps, aeps...)
+	}
+	providers := s3Providers(eps)
+	fps, err := loadProviders(*providerFile, eps)
+	if nil != err {
+		log.Fatalf(
+			"Unable to load providers from %v: %v",
+			*providerFile,
+			err,
+		)
+	}
+	providers = append(providers, fps...)
+
+	/* Trace file, for debugging */
+	trace, err := newTracer(*traceFile)
+	if nil != err {
+		log.Fatalf("Unable to open trace file %v: %v", *traceFile, err)
+	}
+	defer trace.Close()
+
+	/* Cache to prevent duplicate checks */
+	var seen nameSet = newSeenNames(SEENCACHESIZE)
+	if *bloom {
+		if seen, err = newBloomNames(*bloomFP); nil != err {
+			log.Fatalf("Unable to make Bloom filter: %v", err)
+		}
+	}
+	if !*tryWWW {
+		seen.Add("www")
+	}
+
+	/* Start name processor */
+	var (
+		bucketch = make(chan candidate)
+		namech   = make(chan string)
+		found    = newNameSources(*showSource)
+	)
+
+	/* Public suffixes, for splitting domain names */
+	suffixes, err := newSuffixList(*pslFile, *privateSuffixes)
+	if nil != err {
+		log.Fatalf("Unable to load public suffix list: %v", err)
+	}
+
+	/* Inputs which have had a public bucket found, if we care */
+	hits, err := newFirstHits(*firstHit, SEENCACHESIZE)
+	if nil != err {
+		log.Fatalf("Unable to make first hit cache: %v", err)
+	}
+
+	/* Work out date suffixes, if we're adding them */
+	var dates []string
+	if *withDates {
+		if dates, err = dateSuffixes(*dateFrom, *dateTo); nil != err {
+			log.Fatalf("Unable to work out date suffixes: %v", err)
+		}
+		log.Printf(
+			"Appending %v date suffixes, %v through %v",
+			len(dates),
+			dates[0],
+			dates[len(dates)-1],
+		)
+	}
+
+	/* Finish early on ^C, and keep count of what we've done */
+	intr := newInterrupter()
+	totals := &runTotals{}
 
 	/* Generate tags */
-	go processNames(bucketch, namech, tags, seen, *useCTL)
+	nconf := &nameConfig{
+		tags:         tags,
+		dates:        dates,
+		seen:         seen,
+		rules:        rules,
+		domains:      make(map[string]struct{}),
+		withWWW:      *withWWW,
+		maxNames:     *maxNames,
+		found:        found,
+		hits:         hits,
+		suffixes:     suffixes,
+		keepOriginal: *keepOriginal,
+		totals:       totals,
+	}
+	switch {
+	case "" == *replayFile && !*raw:
+		go processNames(bucketch, namech, nconf, *useCTL)
+	case "" == *replayFile:
+		/* Names are already bucket names */
+		go rawNames(bucketch, namech, rules, totals)
+	default:
+		/* Names in the trace have already been processed */
+		go func() {
+			defer close(bucketch)
+			for _, n := range replayNames {
+				totals.Name()
+				bucketch <- candidate{
+					name:   n,
+					source: SourceLiteral,
+					input:  n,
+				}
+			}
+		}()
+	}
+
+	/* What crt.sh said last time, for not asking again */
+	ctlst, err := newCTLState(*ctlStateFile)
+	if nil != err {
+		log.Fatalf(
+			"Unable to load crt.sh state from %v: %v",
+			*ctlStateFile,
+			err,
+		)
+	}
+
+	/* Tracing, for seeing where the time goes */
+	stopOTel, err := startOTel(*otlpEndpoint)
+	if nil != err {
+		log.Fatalf("Unable to start OpenTelemetry exporter: %v", err)
+	}
 
-	/* Filter names through CTL checker, if needed */
+	/* Let the user pause things */
+	pause := newPauser()
+	watchPauseSignal(pause)
+
+	/* Work out where to look for more subdomains */
+	var srcs []subdomainSource
 	if *useCTL {
+		q, err := newCTLQuery(*ctlQuery)
+		if nil != err {
+			log.Fatalf("Invalid -ctl-query: %v", err)
+		}
+		srcs = append(srcs, crtshSource{
+			query:    q,
+			maxBytes: *ctlMaxBytes,
+			state:    ctlst,
+			limiter:  newRateLimiter(*ctlRate),
+			pause:    pause,
+			levels:   *ctlMaxLevels,
+			suffixes: suffixes,
+			queried:  newSeenNames(SEENCACHESIZE),
+		})
+	}
+	if *usePassiveDNS {
+		if "" == *passiveDNSKey {
+			*passiveDNSKey = os.Getenv("SECURITYTRAILS_API_KEY")
+		}
+		if "" == *passiveDNSKey {
+			log.Fatalf("Passive DNS queries need an API key")
+		}
+		srcs = append(srcs, newSecurityTrailsSource(*passiveDNSKey))
+	}
+
+	/* Filter names through subdomain finders, if needed */
+	if 0 != len(srcs) {
+		subs, err := newLineFile(*subdomainsFile, false)
+		if nil != err {
+			log.Fatalf(
+				"Unable to open subdomains file %v: %v",
+				*subdomainsFile,
+				err,
+			)
+		}
+		defer subs.Close()
 		inch := make(chan string)
-		go getCTLNames(namech, inch)
+		go getSubdomainNames(
+			intr.Context(),
+			namech,
+			inch,
+			srcs,
+			subs,
+			found,
+		)
 		namech = inch
 	}
 
-	/* Start checkers */
-	wg := &sync.WaitGroup{}
-	for i := uint(0); i < *nQuery; i++ {
-		wg.Add(1)
-		go checker(
-			bucketch,
-			NRClient,
-			wg,
-			slog,
-			*nonBuckets,
-			*ignoreNotAllowed,
-		)
+	/* Look for buckets sites tell us about, if needed */
+	if *scrape {
+		inch := make(chan string)
+		go getScrapedNames(namech, inch, &http.Client{
+			Transport: NRClient.Transport,
+			Timeout:   SCRAPETIMEOUT,
+		}, found)
+		namech = inch
 	}
 
-	/* Handle names on the command line */
-	if 0 < flag.NArg() {
-		for _, n := range flag.Args() {
-			namech <- n
+	/* Look for buckets behind CloudFront, if needed */
+	if *useCloudFront {
+		inch := make(chan string)
+		go getCloudFrontNames(namech, inch, NRClient, slog)
+		namech = inch
+	}
+
+	/* Credentials for requester-pays checks */
+	var creds *awsCreds
+	if *requesterPays {
+		if creds, err = awsCredsFromEnv(); nil != err {
+			log.Fatalf(
+				"Unable to get AWS credentials for "+
+					"requester-pays checks: %v",
+				err,
+			)
 		}
+		log.Printf(
+			"Will retry forbidden buckets as requester-pays; " +
+				"this may incur AWS charges",
+		)
 	}
 
-	/* Handle names from a file, if we have one */
-	if "" != *nameF {
-		if err := namesFromFile(namech, *nameF); nil != err {
+	/* Note what we're about to do, if asked */
+	if *showConfig {
+		logConfig(tags, rules)
+	}
+
+	/* Prefixes to try on forbidden buckets */
+	var prefixes []string
+	if "" != *prefixFile {
+		if prefixes, err = readPrefixes(*prefixFile); nil != err {
+			log.Fatalf(
+				"Unable to read prefixes from %v: %v",
+				*prefixFile,
+				err,
+			)
+		}
+		if 1 == len(prefixes) {
+			log.Printf("Will try 1 prefix on forbidden buckets")
+		} else {
 			log.Printf(
-				"Error reading names from %v: %v",
-				*nameF,
+				"Will try %v prefixes on forbidden buckets",
+				len(prefixes),
+			)
+		}
+	}
+
+	/* Buckets we already know about */
+	var known map[string]struct{}
+	if "" != *skipKnown {
+		if known, err = readKnown(*skipKnown); nil != err {
+			log.Fatalf(
+				"Unable to read known buckets from %v: %v",
+				*skipKnown,
 				err,
 			)
 		}
+		log.Printf("Will skip %v known buckets", len(known))
+	}
+
+	/* Buckets from previous runs are worth learning from too */
+	learner := newTagLearner("" != *learnTags)
+	for k := range known {
+		learner.Add(k, "")
+	}
+
+	/* Things which want results */
+	var sinks []resultSink
+	if nil != jl {
+		sinks = append(sinks, jl)
+	}
+	final := newFinalReport("" != *finalFile || "" != *sarifFile)
+	if nil != final {
+		sinks = append(sinks, final)
+	}
+	owners := newOwnerGroups(*groupOwners)
+	if nil != owners {
+		sinks = append(sinks, owners)
+	}
+	sock, err := newSocketServer(*socketPath)
+	if nil != err {
+		log.Fatalf("Unable to listen on %v: %v", *socketPath, err)
+	}
+	if nil != sock {
+		defer sock.Close()
+		sinks = append(sinks, sock)
+	}
+	web, err := newWebServer(*webAddr, start)
+	if nil != err {
+		log.Fatalf("Unable to listen on %v: %v", *webAddr, err)
+	}
+	if nil != web {
+		sinks = append(sinks, web)
+	}
+	minSev, err := parseSeverity(*minSeverity)
+	if nil != err {
+		log.Fatalf("Invalid -min-severity: %v", err)
+	}
+	fail, err := newFailPolicy(*failOn)
+	if nil != err {
+		log.Fatalf("Invalid -fail-on: %v", err)
+	}
+	if nil != fail {
+		sinks = append(sinks, fail)
+	}
+
+	/* Don't go faster than we're allowed, or, if asked, than S3 lets us */
+	limiter := newRateLimiter(*checkRate)
+	adaptive := newAdaptiveLimiter(*adaptiveRate)
+	go adaptive.Log()
+
+	listLim := listLimits{
+		maxKeys:  *maxKeys,
+		timeout:  *listTimeout,
+		limiter:  limiter,
+		adaptive: adaptive,
+	}
+	var idone chan struct{}
+	if *interact {
+		if "-" == *nameF {
+			log.Fatalf(
+				"Can't read names from stdin with -interactive",
+			)
+		}
+		in := newInteractive(os.Stderr, NRClient, listLim)
+		sinks = append(sinks, in)
+		idone = make(chan struct{})
+		go func() {
+			defer close(idone)
+			if err := in.Run(os.Stdin); nil != err {
+				elog.Printf("Error reading from stdin: %v", err)
+			}
+		}()
+	}
+
+	/* Output nobody wants */
+	if *ignoreNotAllowed {
+		*ignoreList += "," + IgnoreForbidden
+	}
+	ignore, err := newIgnoreSet(*ignoreList)
+	if nil != err {
+		log.Fatalf("Invalid -ignore: %v", err)
+	}
+
+	/* Request timing */
+	var lats *latencyStats
+	if *timing {
+		lats = &latencyStats{}
+	}
+
+	/* Work out which regions there are, if we need to know.  Otherwise,
+	the regions popular for bucket names will do. */
+	regions := COMPANYREGIONS
+	if *useAllRegions {
+		regions = allRegions()
+		log.Printf("Know of %v AWS regions", len(regions))
+		rs := regionSet(strings.Join(regions, ","))
+		for r := range regionSet(*allowedRegions) {
+			if _, ok := rs[r]; !ok {
+				log.Printf(
+					"Allowed region %v isn't an AWS region",
+					r,
+				)
+			}
+		}
+	}
+
+	/* Names which turned out not to be buckets, for proving we looked */
+	negatives, err := newLineFile(*negativesFile, false)
+	if nil != err {
+		log.Fatalf(
+			"Unable to open negatives file %v: %v",
+			*negativesFile,
+			err,
+		)
+	}
+	defer negatives.Close()
+
+	/* Public buckets, for finding the morning after */
+	public, err := newLineFile(*publicFile, true)
+	if nil != err {
+		log.Fatalf(
+			"Unable to open public bucket file %v: %v",
+			*publicFile,
+			err,
+		)
+	}
+	defer public.Close()
+
+	/* Start checkers */
+	conf := &checkConfig{
+		client:           NRClient,
+		confirm:          confirmClient,
+		slog:             slog,
+		json:             jl,
+		nonBuckets:       *nonBuckets,
+		negatives:        negatives,
+		public:           public,
+		ignore:           ignore,
+		trace:            trace,
+		creds:            creds,
+		providers:        providers,
+		sinks:            sinks,
+		minSeverity:      minSev,
+		latencies:        lats,
+		known:            known,
+		learner:          learner,
+		showSource:       *showSource,
+		oldTLS:           newOldTLSWarner(),
+		adaptive:         adaptive,
+		limiter:          limiter,
+		run:              run,
+		dedup:            newDedupWindow(*dedupWindowLen),
+		requests:         newRequestCounts(),
+		bucketInfo:       *checkVersioning,
+		listKeys:         *listKeys,
+		listLim:          listLim,
+		checkPolicy:      *checkPolicy || *groupOwners,
+		findOwners:       *groupOwners,
+		headRegion:       *headForRegion,
+		prefixes:         prefixes,
+		followRedirects:  *followRedirects,
+		headers:          newHeaderDump(*dumpHeaders),
+		hits:             hits,
+		unexpected:       newRequestCounts(),
+		inFlight:         newInFlight(),
+		pause:            pause,
+		reportUnexpected: *reportUnexpected,
+		allowedRegions:   regionSet(*allowedRegions),
+		interrupt:        intr,
+		totals:           totals,
+	}
+	var (
+		wg     = &sync.WaitGroup{}
+		nCands uint
+	)
+	if "" != *certIssuer && !*watchCerts {
+		log.Fatalf("Can't filter certificates by issuer without -certs")
+	}
+	if *estimate && *watchCerts {
+		log.Fatalf("The certificate stream never ends, can't estimate")
+	}
+	if "" != *grpcAddr && (*estimate || *candidatesOnly) {
+		log.Fatalf("Can't serve gRPC clients without checking names")
+	}
+	gs, err := newGRPCServer(
+		*grpcAddr,
+		conf,
+		rules,
+		*nQuery,
+		*nQuery,
+		*grpcRate,
+	)
+	if nil != err {
+		log.Fatalf("Unable to listen on %v: %v", *grpcAddr, err)
+	}
+	certStatus := newCertStreamStatus(*watchCerts)
+	if nil != web {
+		defer web.Close()
+		web.Serve(conf, certStatus)
+	}
+	if *candidatesOnly {
+		/* Someone else will check them */
+		wg.Add(1)
+		go printCandidates(bucketch, *showSource, wg)
+	} else if *estimate {
+		/* Just count them */
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nCands = countCandidates(bucketch, known)
+		}()
+	} else {
+		/* Start the checkers bit by bit, if we're ramping up, so
+		they don't all make their first requests at once */
+		wg.Add(int(*nQuery))
+		go func() {
+			for i := uint(0); i < *nQuery; i++ {
+				if 0 != i {
+					d := *ramp / time.Duration(*nQuery)
+					time.Sleep(d)
+				}
+				go checker(i, bucketch, wg, conf)
+			}
+		}()
 	}
 
-	/* Handle names from certificate transparency logs */
+	/* Send names to be processed.  Each source gets its own goroutine,
+	and namech is closed when they've all finished.  None of this blocks
+	main so that we can finish when the checkers do, even if there are
+	still names coming in, e.g. with -max-names. */
+	swg := &sync.WaitGroup{}
+
+	/* Work out where names come from.  Names from finite sources go
+	through a shuffler, if we're shuffling.  It's not much use on
+	never-ending certs or a followed file. */
+	var finite, endless []inputSource
+	if 0 < flag.NArg() {
+		finite = append(finite, listSource{
+			desc:  "the command line",
+			names: argNames(),
+		})
+	}
+	if "" != *company {
+		cns := companyNames(*company, *companyProducts, regions)
+		if 0 == len(cns) {
+			log.Fatalf("No names could be made from %q", *company)
+		}
+		log.Printf(
+			"Generated %v names from company name %q",
+			len(cns),
+			*company,
+		)
+		finite = append(finite, listSource{
+			desc:  "company name " + *company,
+			names: cns,
+		})
+	}
+	if "" != *certFile {
+		finite = append(finite, funcSource{
+			desc: "certificates in " + *certFile,
+			f: func(_ context.Context, c chan<- string) error {
+				return namesFromCertFile(c, *certFile)
+			},
+		})
+	}
+	if "" != *zoneFile {
+		finite = append(finite, funcSource{
+			desc: "zone file " + *zoneFile,
+			f: func(_ context.Context, c chan<- string) error {
+				return namesFromZoneFile(c, *zoneFile)
+			},
+		})
+	}
+	if "" != *nameF {
+		/* If we're following the file, stop when we're
+		interrupted. */
+		var stop chan struct{}
+		if *follow {
+			stop = make(chan struct{})
+			go func() {
+				<-intr.Context().Done()
+				log.Printf("No longer following %v", *nameF)
+				close(stop)
+			}()
+		}
+		src := funcSource{
+			desc: *nameF,
+			f: func(_ context.Context, c chan<- string) error {
+				return namesFromFile(c, *nameF, stop)
+			},
+		}
+		if *follow {
+			endless = append(endless, src)
+		} else {
+			finite = append(finite, src)
+		}
+	}
 	if *watchCerts {
-		watchLogs(namech)
+		endless = append(endless, funcSource{
+			desc: "the certificate stream",
+			f: func(ctx context.Context, c chan<- string) error {
+				watchLogs(ctx, c, certStatus, *certIssuer)
+				return nil
+			},
+		})
 	}
 
-	close(namech)
+	/* Fan the sources in to namech, until we're interrupted */
+	ctx := intr.Context()
+	finch := namech
+	if *shuffle {
+		finch = make(chan string)
+		swg.Add(1)
+		go func() {
+			defer swg.Done()
+			shuffleNames(namech, finch, SHUFFLEMAX)
+		}()
+	}
+	swg.Add(2)
+	go func() {
+		defer swg.Done()
+		mergeNames(ctx, finch, finite)
+		/* Only once the finite sources are done can the shuffler
+		finish */
+		if *shuffle {
+			close(finch)
+		}
+	}()
+	go func() {
+		defer swg.Done()
+		mergeNames(ctx, namech, endless)
+	}()
+
+	/* Close namech when all the sources are done */
+	go func() {
+		swg.Wait()
+		close(namech)
+	}()
 
 	/* Wait for checkers to finish */
 	wg.Wait()
+
+	/* Keep serving gRPC clients until we're told to stop, unless we
+	already have been */
+	intr.Stop()
+	if nil != gs && !intr.Interrupted() {
+		sigch := make(chan os.Signal, 1)
+		signal.Notify(sigch, os.Interrupt)
+		log.Printf(
+			"Finished checking, serving gRPC clients until " +
+				"interrupted",
+		)
+		<-sigch
+		signal.Stop(sigch)
+	}
+	if nil != gs {
+		gs.Close()
+	}
+
+	/* Report the breadth of what we saw.  processNames is done by now, as
+	it closes bucketch on return. */
+	if 1 == len(nconf.domains) {
+		log.Printf("Saw 1 unique registrable domain")
+	} else {
+		log.Printf(
+			"Saw %v unique registrable domains",
+			len(nconf.domains),
+		)
+	}
+	if "" != *learnTags {
+		if n, err := learner.Write(*learnTags); nil != err {
+			elog.Printf(
+				"Error writing tags to %v: %v",
+				*learnTags,
+				err,
+			)
+		} else {
+			log.Printf("Wrote %v tags to %v", n, *learnTags)
+		}
+	}
+	owners.Log()
+	if "" != *finalFile {
+		if n, err := final.Write(*finalFile); nil != err {
+			elog.Printf(
+				"Error writing report to %v: %v",
+				*finalFile,
+				err,
+			)
+		} else if 1 == n {
+			log.Printf("Wrote 1 finding to %v", *finalFile)
+		} else {
+			log.Printf("Wrote %v findings to %v", n, *finalFile)
+		}
+	}
+	if "" != *sarifFile {
+		if err := writeSARIF(
+			*sarifFile,
+			final.Results(),
+			start,
+			run.String(),
+		); nil != err {
+			elog.Printf(
+				"Error writing SARIF to %v: %v",
+				*sarifFile,
+				err,
+			)
+		} else {
+			log.Printf("Wrote SARIF to %v", *sarifFile)
+		}
+	}
+	if "" != *domainsFile {
+		if err := writeDomains(
+			*domainsFile,
+			nconf.domains,
+		); nil != err {
+			elog.Printf(
+				"Error writing domains to %v: %v",
+				*domainsFile,
+				err,
+			)
+		}
+	}
+
+	/* How much did we do, and how fast was it? */
+	if *estimate {
+		log.Printf(
+			"Estimate: %v unique names, %v endpoint sets, "+
+				"at least %v requests",
+			nCands,
+			len(providers),
+			nCands*uint(len(providers)),
+		)
+	} else if !*candidatesOnly {
+		log.Printf("Requests: %v", conf.requests)
+		log.Printf("Unexpected responses: %v", conf.unexpected)
+		log.Printf("Suppressed output: %v", conf.ignore)
+		if nil != conf.adaptive {
+			log.Printf("Adaptive rate: %v", conf.adaptive)
+		}
+		if nil != conf.dedup {
+			log.Printf(
+				"Checks of recently-found buckets skipped: %v",
+				conf.dedup.Skipped(),
+			)
+		}
+		log.Printf(
+			"Duplicate checks coalesced: %v",
+			conf.inFlight.Coalesced(),
+		)
+		log.Printf(
+			"Finished in %v: %v",
+			time.Since(start).Round(time.Millisecond),
+			totals,
+		)
+	}
+	if nil != lats {
+		log.Printf("Request latency: %v", lats)
+	}
+
+	/* Let the user keep looking at what we found */
+	if nil != idone {
+		log.Printf("Finished checking, press Ctrl+D when done listing")
+		<-idone
+	}
+
+	/* Save the report one last time */
+	rep.Close()
+
+	/* Save what crt.sh told us, for next time */
+	if err := ctlst.Save(); nil != err {
+		elog.Printf(
+			"Error saving crt.sh state to %v: %v",
+			*ctlStateFile,
+			err,
+		)
+	}
+
+	stopOTel()
 	log.Printf("Done.")
+
+	/* Let whoever started us know if we found something bad */
+	if n := fail.Failed(); 0 != n {
+		if 1 == n {
+			log.Printf("Found 1 bucket covered by -fail-on")
+		} else {
+			log.Printf("Found %v buckets covered by -fail-on", n)
+		}
+		/* Deferred functions don't run on os.Exit */
+		trace.Close()
+		if nil != sock {
+			sock.Close()
+		}
+		os.Exit(FAILEXITCODE)
+	}
+}
+
+/* logConfig logs the value of every flag, with secrets redacted, as well as
+the number of tags and the naming rules in use. */
+func logConfig(tags []string, rules namingRules) {
+	/* Work out which flags were given */
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	/* Log them all */
+	log.Printf("Configuration:")
+	flag.VisitAll(func(f *flag.Flag) {
+		d := "default"
+		if set[f.Name] {
+			d = "set"
+		}
+		log.Printf(
+			"\t-%v=%q (%v)",
+			f.Name,
+			redactedFlag(f.Name, f.Value.String()),
+			d,
+		)
+	})
+	log.Printf("\tTags: %v", len(tags))
+	log.Printf(
+		"\tNaming rules: characters=%q max_label_length=%v "+
+			"min_length=%v max_length=%v lowercase=%v",
+		rules.chars,
+		rules.maxLabelLen,
+		rules.minLen,
+		rules.maxLen,
+		rules.lower,
+	)
+	log.Printf("\tNames on the command line: %v", flag.NArg())
 }
 
 /* namesFromFile sends the non-comment, non-blank lines of the file named n to
-c. */
-func namesFromFile(c chan<- string, n string) error {
+c, with URLs reduced to their hostnames.  If stop is not nil and n isn't -,
+namesFromFile keeps waiting for more lines to be appended to the file until
+stop is closed. */
+func namesFromFile(c chan<- string, n string, stop <-chan struct{}) error {
 	f := os.Stdin
 
 	/* Try to open file if we have a name */
@@ -244,6 +1881,11 @@ func namesFromFile(c chan<- string, n string) error {
 			return err
 		}
 		defer f.Close()
+
+		/* Keep reading, if we're meant to */
+		if nil != stop {
+			return followFile(c, f, stop)
+		}
 	}
 
 	/* Read lines, send to c */
@@ -255,7 +1897,7 @@ func namesFromFile(c chan<- string, n string) error {
 			continue
 		}
 		/* Send line to channel */
-		c <- l
+		c <- nameFromURL(l)
 	}
 	if err := scanner.Err(); nil != err {
 		return err
@@ -263,210 +1905,1388 @@ func namesFromFile(c chan<- string, n string) error {
 	return nil
 }
 
-/* watchLogs sends names from certificate transparency logs to namech.  It
-returns on error. */
-func watchLogs(namech chan<- string) {
-	/* Open the cert stream */
-	certs, errs := certstream.CertStreamEventStream(true)
-	log.Printf("Made certificate stream")
-CERTLOOP:
-	for {
-		select {
-		case cert, ok := <-certs: /* Got a new cert */
-			if !ok {
-				log.Printf("End of certificate stream")
-				certs = nil
-				break
-			}
-			/* Pull out domains for which the cert is valid */
-			names, err := cert.ArrayOfStrings(
-				"data",
+/* shuffleNames sends the names it receives on in to out in a random order.
+At most max names are buffered; when the buffer fills, its names are shuffled
+and sent before any more are read. */
+func shuffleNames(out chan<- string, in <-chan string, max int) {
+	var (
+		buf []string
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	)
+	/* flush shuffles and sends what's in the buffer */
+	flush := func() {
+		rnd.Shuffle(len(buf), func(i, j int) {
+			buf[i], buf[j] = buf[j], buf[i]
+		})
+		for _, n := range buf {
+			out <- n
+		}
+		buf = buf[:0]
+	}
+	for n := range in {
+		buf = append(buf, n)
+		if max <= len(buf) {
+			flush()
+		}
+	}
+	flush()
+}
+
+/* followFile is like tail -f; it sends the non-comment, non-blank lines of f
+to c, with URLs reduced to their hostnames, waiting for more lines after
+reaching the end of the file, until stop is closed. */
+func followFile(c chan<- string, f *os.File, stop <-chan struct{}) error {
+	var (
+		r    = bufio.NewReader(f)
+		part string /* Partial line, before EOF */
+	)
+	for {
+		/* Get a line, or what's there of one */
+		l, err := r.ReadString('\n')
+		part += l
+		if io.EOF == err {
+			/* Wait for more to be written */
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(FOLLOWWAIT):
+			}
+			continue
+		} else if nil != err {
+			return err
+		}
+		l, part = strings.TrimSpace(part), ""
+
+		/* Skip blank lines and comments */
+		if "" == l || strings.HasPrefix(l, "#") {
+			continue
+		}
+		c <- nameFromURL(l)
+	}
+}
+
+/* watchLogs sends names from certificate transparency logs to namech, noting
+how it's going in status.  If issuer isn't the empty string, only names from
+certificates with an issuer field containing issuer, ignoring case, are sent.
+It returns when the certificate stream ends or ctx is done. */
+func watchLogs(
+	ctx context.Context,
+	namech chan<- string,
+	status *certStreamStatus,
+	issuer string,
+) {
+	/* Open the cert stream */
+	certs, errs := certstream.CertStreamEventStream(true)
+	log.Printf("Made certificate stream")
+	status.SetConnected(true)
+	for {
+		select {
+		case <-ctx.Done(): /* Told to stop */
+			status.SetConnected(false)
+			return
+		case cert, ok := <-certs: /* Got a new cert */
+			if !ok {
+				log.Printf("End of certificate stream")
+				status.SetConnected(false)
+				return
+			}
+			/* Skip certs from CAs we don't care about */
+			if "" != issuer && !certIssuedBy(cert, issuer) {
+				continue
+			}
+			/* Pull out domains for which the cert is valid */
+			names, err := cert.ArrayOfStrings(
+				"data",
 				"leaf_cert",
 				"all_domains",
 			)
 			if nil != err {
-				log.Printf("Certificate error: %v", err)
+				elog.Printf("Certificate error: %v", err)
 				continue
 			}
 			/* Send them to be checked */
-			for _, name := range names {
-				/* Don't query for wildcards */
-				if strings.Contains(name, "*") {
-					continue
-				}
-				namech <- name
-			}
+			status.Cert(len(names))
+			sendCertNames(namech, names)
 		case err, ok := <-errs: /* Stream error of some sort */
 			if !ok {
+				/* Not much to do but keep getting certs */
 				log.Printf("End of error stream")
 				errs = nil
-				break CERTLOOP
+				break
+			}
+			elog.Fatalf("Certificate stream error: %v", err)
+		}
+	}
+}
+
+/* certIssuedBy returns true if any of the fields of the issuer of the leaf
+certificate in cert contain issuer, ignoring case. */
+func certIssuedBy(cert jsonq.JsonQuery, issuer string) bool {
+	fs, err := cert.Object("data", "leaf_cert", "issuer")
+	if nil != err {
+		return false
+	}
+	issuer = strings.ToLower(issuer)
+	for _, v := range fs {
+		s, ok := v.(string)
+		if ok && strings.Contains(strings.ToLower(s), issuer) {
+			return true
+		}
+	}
+	return false
+}
+
+/* sendCertNames sends the names from a certificate to namech.  Leading
+wildcard labels are removed, so *.assets.example.com is sent as
+assets.example.com, which processNames turns into, among others,
+assets-example-com.  Other wildcards are skipped. */
+func sendCertNames(namech chan<- string, names []string) {
+	for _, name := range names {
+		/* A wildcard's parent is still a good name, and its
+		hyphenated form a common bucket name */
+		name = strings.TrimPrefix(name, "*.")
+		/* Don't query for other wildcards */
+		if strings.Contains(name, "*") {
+			continue
+		}
+		namech <- name
+	}
+}
+
+/* printCandidates prints the names sent on bucketch to stdout, without
+duplicates.  If showSource is true, each name is followed by a tab and how it
+was generated. */
+func printCandidates(
+	bucketch <-chan candidate,
+	showSource bool,
+	wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+	var (
+		lf   = &lineFile{f: os.Stdout, seen: make(map[string]struct{})}
+		seen = make(map[string]struct{})
+	)
+	for b := range bucketch {
+		/* Only the first source for a name is printed */
+		if _, ok := seen[b.name]; ok {
+			continue
+		}
+		seen[b.name] = struct{}{}
+		l := b.name
+		if showSource {
+			l += "\t" + b.source
+		}
+		if err := lf.WriteLine(l); nil != err {
+			elog.Fatalf(
+				"Error writing possible bucket name: %v",
+				err,
+			)
+		}
+	}
+}
+
+/* regionSet returns the regions in the comma-separated list s as a set, or nil
+if s is the empty string. */
+func regionSet(s string) map[string]struct{} {
+	if "" == s {
+		return nil
+	}
+	m := make(map[string]struct{})
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); "" != r {
+			m[r] = struct{}{}
+		}
+	}
+	return m
+}
+
+/* countCandidates returns the number of unique names sent on bucketch which
+aren't in known. */
+func countCandidates(
+	bucketch <-chan candidate,
+	known map[string]struct{},
+) uint {
+	seen := make(map[string]struct{})
+	for b := range bucketch {
+		if _, ok := known[b.name]; ok {
+			continue
+		}
+		seen[b.name] = struct{}{}
+	}
+	return uint(len(seen))
+}
+
+/* checkConfig holds the settings shared by every check. */
+type checkConfig struct {
+	/* client makes requests to see if names are S3 buckets */
+	client *http.Client
+
+	/* confirm, if not nil, repeats requests which found buckets, using a
+	new connection each time */
+	confirm *http.Client
+
+	/* slog logs successes */
+	slog *log.Logger
+
+	/* json, if not nil, writes results and the steps along the way to
+	them as JSON lines */
+	json *jsonLines
+
+	/* nonBuckets causes names which aren't buckets to be printed */
+	nonBuckets bool
+
+	/* negatives gets the names which aren't buckets */
+	negatives *lineFile
+
+	/* public gets the URLs of public buckets */
+	public *lineFile
+
+	/* limiter, if not nil, limits how quickly checks' requests are
+	made, across all checkers */
+	limiter *rateLimiter
+
+	/* interrupt stops new checks from being started */
+	interrupt *interrupter
+
+	/* totals counts candidates checked and public buckets found */
+	totals *runTotals
+
+	/* ignore suppresses output nobody wants */
+	ignore *ignoreSet
+
+	/* trace records every request and response */
+	trace *tracer
+
+	/* creds, if not nil, are used to retry forbidden buckets as
+	requester-pays */
+	creds *awsCreds
+
+	/* providers are the places to look for each name's bucket */
+	providers []provider
+
+	/* sinks are sent results */
+	sinks []resultSink
+
+	/* minSeverity is the least severity a result needs to be sent to
+	sinks, or the empty string for any result */
+	minSeverity string
+
+	/* latencies, if not nil, records how long requests take */
+	latencies *latencyStats
+
+	/* known are names of buckets already found, which won't be checked
+	again */
+	known map[string]struct{}
+
+	/* learner learns tags from the names of buckets which exist */
+	learner *tagLearner
+
+	/* showSource causes how names were generated to be included in
+	output */
+	showSource bool
+
+	/* oldTLS notes endpoints which negotiate old TLS versions */
+	oldTLS *oldTLSWarner
+
+	/* adaptive, if not nil, spaces out bucket checks and slows them
+	down when S3 asks */
+	adaptive *adaptiveLimiter
+
+	/* run, if not nil, identifies this run in results */
+	run *runInfo
+
+	/* dedup, if not nil, keeps recently-found buckets from being checked
+	and reported again */
+	dedup *dedupWindow
+
+	/* requests counts the requests made to each set of endpoints */
+	requests *requestCounts
+
+	/* bucketInfo causes public buckets' versioning and lifecycle
+	subresources to be checked */
+	bucketInfo bool
+
+	/* listKeys causes the objects in public buckets to be listed, within
+	listLim */
+	listKeys bool
+	listLim  listLimits
+
+	/* followRedirects causes redirects away from S3 to be reported */
+	followRedirects bool
+
+	/* checkPolicy causes public and forbidden buckets' policy, ACL, and
+	CORS subresources to be checked */
+	checkPolicy bool
+
+	/* findOwners causes public buckets' owners to be taken from their
+	listings */
+	findOwners bool
+
+	/* headRegion causes buckets' regions to be found with a HEAD request
+	before they're checked */
+	headRegion bool
+
+	/* prefixes are tried when listing a whole bucket is forbidden */
+	prefixes []string
+
+	/* headers picks the response headers to put in results for buckets
+	which exist */
+	headers *headerDump
+
+	/* hits notes which inputs have had a public bucket found, so the rest
+	of their names needn't be checked */
+	hits *firstHits
+
+	/* unexpected counts the unexpected responses we've had, by status */
+	unexpected *requestCounts
+
+	/* reportUnexpected causes unexpected responses to be reported like
+	found buckets */
+	reportUnexpected bool
+
+	/* allowedRegions, if not nil, are the only regions to which we'll
+	follow buckets */
+	allowedRegions map[string]struct{}
+
+	/* pause pauses checks */
+	pause *pauser
+
+	/* inFlight coalesces concurrent checks of the same name against the
+	same endpoints */
+	inFlight *inFlight
+}
+
+/* regionAllowed returns true if the bucket cand, at bucketURL, may be checked
+in region.  If not, it's reported as existing in region, using the response
+res which took lat to get. */
+func (c *checkConfig) regionAllowed(
+	cand candidate,
+	bucketURL string,
+	region string,
+	res *http.Response,
+	lat time.Duration,
+) bool {
+	if nil == c.allowedRegions {
+		return true
+	}
+	if _, ok := c.allowedRegions[region]; ok {
+		return true
+	}
+	log.Printf(
+		"[%v] Bucket exists in %v, which isn't allowed (%v)%v",
+		cand.name,
+		region,
+		bucketURL,
+		c.via(cand),
+	)
+	c.emit(cand, bucketURL, StatusRegionNotAllowed, res, region, lat, nil)
+	return false
+}
+
+/* wait waits until c's rate limits allow another request, or ctx is done, in
+which case it returns false. */
+func (c *checkConfig) wait(ctx context.Context) bool {
+	return waitTurn(ctx, c.limiter, c.adaptive)
+}
+
+/* negative notes that n isn't a bucket. */
+func (c *checkConfig) negative(n string) {
+	if err := c.negatives.WriteLine(n); nil != err {
+		elog.Printf("[%v] Error writing negative: %v", n, err)
+	}
+}
+
+/* via returns a description of how cand was generated, suitable for appending
+to a message, or the empty string if c.showSource is false. */
+func (c *checkConfig) via(cand candidate) string {
+	if !c.showSource {
+		return ""
+	}
+	return fmt.Sprintf(" (via %v)", cand.source)
+}
+
+/* emit sends a Result describing a check of the candidate cand which got the
+response res after lat to each of c's sinks.  If info isn't nil, what it knows
+is included as well.  The result's time is set to the current time. */
+func (c *checkConfig) emit(
+	cand candidate,
+	bucketURL string,
+	status string,
+	res *http.Response,
+	region string,
+	lat time.Duration,
+	info *bucketInfo,
+) {
+	switch status {
+	case StatusNotBucket, StatusUnexpected:
+	default:
+		c.learner.Add(cand.name, cand.input)
+	}
+	c.send(c.result(cand, bucketURL, status, res, region, lat, info))
+}
+
+/* result returns the Result which emit would send. */
+func (c *checkConfig) result(
+	cand candidate,
+	bucketURL string,
+	status string,
+	res *http.Response,
+	region string,
+	lat time.Duration,
+	info *bucketInfo,
+) Result {
+	r := Result{
+		Name:      cand.name,
+		BucketURL: bucketURL,
+		Status:    status,
+		Region:    region,
+		Time:      time.Now(),
+	}
+	if nil != res {
+		r.HTTPStatus = res.StatusCode
+		/* Headers for every 404 would be a bit much */
+		if StatusNotBucket != status {
+			r.Headers = c.headers.Headers(res.Header)
+		}
+	}
+	if nil != c.latencies {
+		r.LatencyMS = float64(lat) / float64(time.Millisecond)
+	}
+	if c.showSource {
+		r.Source = cand.source
+	}
+	if nil != info {
+		r.Versioning = info.versioning
+		if 0 <= info.lifecycleRules {
+			r.LifecycleRules = &info.lifecycleRules
+		}
+		r.Documents = info.documents
+		r.OwnerID = info.owner.ID
+		r.OwnerName = info.owner.DisplayName
+	}
+	return r
+}
+
+/* send notes r's bucket as found, if it's a bucket, tags r with c's run, works
+out r's severity, and sends it to each of c's sinks, unless it's not as severe
+as c.minSeverity. */
+func (c *checkConfig) send(r Result) {
+	switch r.Status {
+	case StatusNotBucket, StatusUnexpected:
+	default:
+		c.dedup.Found(r.Name)
+	}
+	c.run.Tag(&r)
+	r.Severity = severityOf(r)
+	if !severityAtLeast(r.Severity, c.minSeverity) {
+		return
+	}
+	for _, s := range c.sinks {
+		s.Send(r)
+	}
+}
+
+/* checker checks if the domain names sent on namech are public s3 buckets,
+according to conf.  The worker number is used to identify this checker in the
+trace. */
+func checker(
+	worker uint,
+	bucketch <-chan candidate,
+	wg *sync.WaitGroup,
+	conf *checkConfig,
+) {
+	defer wg.Done()
+	for {
+		/* Don't take more names while we're paused */
+		conf.pause.Wait()
+		bucket, ok := <-bucketch
+		if !ok {
+			return
+		}
+		conf.totals.Candidate()
+		/* Don't start anything new once we've been interrupted */
+		if conf.interrupt.Interrupted() {
+			continue
+		}
+		/* Don't bother with buckets we already know about */
+		if _, ok := conf.known[bucket.name]; ok {
+			continue
+		}
+		/* Or which we've found recently */
+		if conf.dedup.Skip(bucket.name) {
+			continue
+		}
+		/* Check each name with each provider, until we find
+		something, if we only want the first hit */
+		ctx, span := startCandidateSpan(
+			conf.hits.Context(bucket.input),
+			bucket,
+		)
+		for _, p := range conf.providers {
+			if conf.hits.Hit(bucket.input) {
+				break
+			}
+			/* The same name may be generated more than once
+			before it's in the seen cache, no need to check it
+			twice at once. */
+			conf.inFlight.Do(bucket.name+" "+p.String(), func() {
+				p.Check(ctx, bucket, worker, conf)
+			})
+		}
+		span.End()
+	}
+}
+
+/* check checks if cand is a domain pointing to a publically-accessible s3
+bucket using the endpoints ep, according to conf.  rem controlls how many
+recurions remain before we give up.  The worker number is recorded in the
+trace.  The check is abandoned if ctx is done. */
+func check(
+	ctx context.Context,
+	cand candidate,
+	region string,
+	ep endpoints,
+	rem uint,
+	worker uint,
+	conf *checkConfig,
+) {
+	n := cand.name
+
+	/* Make sure we're allowed to recurse */
+	if 0 == rem {
+		if !conf.ignore.Suppress(IgnoreExhausted) {
+			elog.Printf("[%v] Too many attempts", n)
+		}
+		return
+	}
+
+	/* Ask where the bucket is before looking in it, if we're meant to */
+	if "" == region && conf.headRegion && !ep.virtual {
+		r, ok := headRegion(ctx, cand, ep, worker, conf)
+		if !ok {
+			return
+		}
+		region = r
+	}
+
+	/* Check if it's an S3 bucket */
+	req, err := http.NewRequest("GET", ep.url(n, region), nil)
+	if nil != err {
+		elog.Printf("[%v] Bucket name creates invalid URL: %v", n, err)
+		return
+	}
+	if ep.inHost() {
+		req.Host = n
+	}
+	req = req.WithContext(ctx)
+	if !conf.wait(ctx) {
+		return
+	}
+	start := time.Now()
+	res, err := conf.client.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add(ep.name)
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
+	spanRequest(ctx, ep.name, req, res, err, lat)
+	took := conf.latencies.Describe(lat)
+
+	/* URL for bucket */
+	bucketURL := req.URL.String()
+	if ep.inHost() {
+		bucketURL += "/" + n
+	}
+
+	/* Handle request errors */
+	if nil != err {
+		/* Someone else found a public bucket for the same input */
+		if nil != req.Context().Err() {
+			return
+		}
+		/* Names which don't resolve won't start resolving, but it's
+		worth knowing which */
+		var dnse *net.DNSError
+		if errors.As(err, &dnse) {
+			if conf.ignore.Suppress(IgnoreError) {
+				return
+			}
+			elog.Printf(
+				"[%v] Unable to resolve %v (%v): %v",
+				n,
+				dnse.Name,
+				bucketURL,
+				dnse.Err,
+			)
+			return
+		}
+		/* Try again if it's the network's fault */
+		why := retryReason(err)
+		if "" == why {
+			/* Any other error is probably fatal for this name */
+			if !conf.ignore.Suppress(IgnoreError) {
+				elog.Printf(
+					"[%v] Bucket check error: %v",
+					n,
+					err,
+				)
+			}
+			return
+		}
+		/* Wait for temporary problems to resolve */
+		log.Printf("[%v] Retrying due to %v", bucketURL, why)
+		conf.json.Retry(cand, bucketURL, why)
+		time.Sleep(RETRYWAIT)
+		check(ctx, cand, region, ep, rem-1, worker, conf)
+		return
+	}
+	/* Note endpoints stuck in the past */
+	conf.oldTLS.Check(req.URL.Host, res.TLS)
+
+	/* Slow down and try again if we're asked to */
+	if http.StatusServiceUnavailable == res.StatusCode &&
+		nil != conf.adaptive {
+		res.Body.Close()
+		conf.adaptive.Throttled()
+		log.Printf("[%v] Slow down (%v), retrying", n, bucketURL)
+		conf.json.Retry(cand, bucketURL, "slow down")
+		check(ctx, cand, region, ep, rem-1, worker, conf)
+		return
+	}
+	conf.adaptive.OK()
+
+	/* Providers other than S3 may mean by one status what S3 means by
+	another */
+	code := ep.status(res.StatusCode)
+
+	/* Bad requests usually say why, and responses we don't expect
+	might */
+	var (
+		s3e  s3Error
+		body []byte
+	)
+	var lo bucketOwner
+	switch code {
+	case 200:
+		/* Listings may say who owns the bucket */
+		if conf.findOwners {
+			lo = ownerFromListing(res.Body)
+		}
+	case 302, 307, 404:
+	case 400, 403:
+		s3e = readS3Error(res.Body)
+	default:
+		body, _ = ioutil.ReadAll(io.LimitReader(
+			res.Body,
+			S3ERRORMAXBODY,
+		))
+	}
+	res.Body.Close()
+
+	/* Buckets which send us elsewhere may be open redirects, unless
+	the provider's said what its redirects mean */
+	if u, ok := offS3Redirect(req, res); ok && conf.followRedirects &&
+		code == res.StatusCode {
+		conf.slog.Printf(
+			"[%v] Redirect (%v) to %v%v%v",
+			n,
+			bucketURL,
+			u,
+			took,
+			conf.via(cand),
+		)
+		conf.learner.Add(n, cand.input)
+		r := conf.result(
+			cand,
+			bucketURL,
+			StatusRedirect,
+			res,
+			region,
+			lat,
+			nil,
+		)
+		r.RedirectURL = u.String()
+		r.RedirectHost = u.Hostname()
+		conf.send(r)
+		return
+	}
+
+	/* Make sure findings aren't flukes */
+	switch code {
+	case 200, 403:
+		if !confirmCheck(
+			cand,
+			req,
+			bucketURL,
+			res.StatusCode,
+			worker,
+			conf,
+		) {
+			return
+		}
+	}
+
+	/* See what happens */
+	switch code {
+	case 200: /* Public bucket */
+		conf.hits.Found(cand.input)
+		/* See if there's more to learn */
+		var (
+			info *bucketInfo
+			desc string
+		)
+		if conf.bucketInfo || conf.checkPolicy {
+			i := getBucketInfo(cand, req, worker, conf)
+			info = &i
+		}
+		if "" != lo.ID {
+			if nil == info {
+				info = &bucketInfo{lifecycleRules: -1}
+			}
+			/* The bucket's ACL is more reliable */
+			if "" == info.owner.ID {
+				info.owner = lo
+			}
+		}
+		if nil != info {
+			desc = info.String()
+		}
+		conf.slog.Printf(
+			"[%v] Public bucket: %v%v%v%v",
+			n,
+			bucketURL,
+			took,
+			conf.via(cand),
+			desc,
+		)
+		conf.totals.Public()
+		if err := conf.public.WriteLine(bucketURL); nil != err {
+			elog.Printf(
+				"[%v] Error writing public bucket: %v",
+				n,
+				err,
+			)
+		}
+		conf.emit(
+			cand,
+			bucketURL,
+			StatusPublic,
+			res,
+			region,
+			lat,
+			info,
+		)
+		if conf.listKeys {
+			logBucketKeys(cand, bucketURL, conf)
+		}
+	case 307: /* Redirect, it's probably an S3 bucket in another region */
+		loc := res.Header.Get("location")
+		rr := res.Header.Get("x-amz-bucket-region")
+		if "" == rr {
+			rr = regionFromLocation(loc)
+		}
+		/* Endpoints which don't say where to go, or which are the
+		same everywhere, won't get any better */
+		if "" == rr || "" == ep.regional {
+			log.Printf("[%v] Unexpected redirect to %q", n, loc)
+			return
+		}
+		/* We shouldn't be redirected to the default region */
+		if "us-east-1" == rr {
+			log.Printf("[%v] Unexpected redirect to %q", n, loc)
+		}
+		/* Check with new region in URL, if we can */
+		if !conf.regionAllowed(cand, bucketURL, rr, res, lat) {
+			return
+		}
+		conf.json.Region(cand, bucketURL, rr)
+		check(ctx, cand, rr, ep, rem-1, worker, conf)
+	case 302: /* Temporary redirect, usually for a brand new bucket */
+		loc := res.Header.Get("location")
+		rr := res.Header.Get("x-amz-bucket-region")
+		if "" == rr {
+			rr = regionFromLocation(loc)
+		}
+		/* Only worth trying again somewhere new */
+		if "" == rr || rr == region || "" == ep.regional {
+			log.Printf("[%v] Unexpected redirect to %q", n, loc)
+			return
+		}
+		log.Printf(
+			"[%v] Temporary redirect to %v, trying again",
+			n,
+			rr,
+		)
+		if !conf.regionAllowed(cand, bucketURL, rr, res, lat) {
+			return
+		}
+		conf.json.Region(cand, bucketURL, rr)
+		check(ctx, cand, rr, ep, rem-1, worker, conf)
+	case 400: /* Bad request */
+		/* Names S3 doesn't like won't get any better */
+		if "InvalidBucketName" == s3e.Code {
+			if !conf.ignore.Suppress(IgnoreBadRequest) {
+				log.Printf("[%v] Invalid bucket name", n)
 			}
-			log.Fatalf("Certificate stream error: %v", err)
+			return
+		}
+		/* We may have been told the right region */
+		rr := s3e.Region
+		if "" == rr {
+			rr = res.Header.Get("x-amz-bucket-region")
+		}
+		if "" != rr && rr != region {
+			log.Printf(
+				"[%v] Bad request (%v), retrying in %v: %v",
+				n,
+				bucketURL,
+				rr,
+				s3e.Code,
+			)
+			if !conf.regionAllowed(cand, bucketURL, rr, res, lat) {
+				return
+			}
+			conf.json.Region(cand, bucketURL, rr)
+			check(ctx, cand, rr, ep, rem-1, worker, conf)
+			return
+		}
+		/* Some buckets only work with name.s3.amazonaws.com, but
+		dotted names won't work with TLS. */
+		if !ep.virtual && !ep.notS3 && !strings.Contains(n, ".") {
+			log.Printf(
+				"[%v] Bad request (%v), retrying "+
+					"virtual-hosted-style",
+				n,
+				bucketURL,
+			)
+			check(
+				ctx,
+				cand,
+				region,
+				VIRTUALENDPOINTS,
+				rem-1,
+				worker,
+				conf,
+			)
+			return
+		}
+		if conf.ignore.Suppress(IgnoreBadRequest) {
+			return
+		}
+		if "" != s3e.Code {
+			log.Printf(
+				"[%v] Bad request (%v): %v",
+				n,
+				bucketURL,
+				s3e.Code,
+			)
+			return
+		}
+		log.Printf("[%v] Bad request (%v)", n, bucketURL)
+		return
+	case 403: /* Bucket, but forbidden */
+		/* Buckets in suspended accounts can't be read by anybody,
+		but the name may be up for grabs once the account's closed */
+		if "AllAccessDisabled" == s3e.Code {
+			conf.slog.Printf(
+				"[%v] Suspended (%v)%v%v",
+				n,
+				bucketURL,
+				took,
+				conf.via(cand),
+			)
+			conf.emit(
+				cand,
+				bucketURL,
+				StatusSuspended,
+				res,
+				res.Header.Get("x-amz-bucket-region"),
+				lat,
+				nil,
+			)
+			return
+		}
+		/* Might be readable if we pay */
+		if nil != conf.creds && checkRequesterPays(
+			cand,
+			req,
+			bucketURL,
+			res.Header.Get("x-amz-bucket-region"),
+			worker,
+			conf,
+		) {
+			return
+		}
+		/* Readable policies are worth a look even if the bucket
+		isn't */
+		var info *bucketInfo
+		if conf.checkPolicy {
+			i := getBucketInfo(cand, req, worker, conf)
+			info = &i
+		}
+		/* Buckets which can't be listed as a whole sometimes can be
+		under a prefix */
+		var ps []string
+		if 0 != len(conf.prefixes) {
+			ps = readablePrefixes(cand, req, worker, conf)
+		}
+		if 0 != len(ps) {
+			var desc string
+			if nil != info {
+				desc = info.String()
+			}
+			conf.slog.Printf(
+				"[%v] Forbidden (%v), but readable "+
+					"under %v%v%v%v",
+				n,
+				bucketURL,
+				strings.Join(ps, ", "),
+				took,
+				conf.via(cand),
+				desc,
+			)
+			conf.learner.Add(n, cand.input)
+			r := conf.result(
+				cand,
+				bucketURL,
+				StatusPublicPrefix,
+				res,
+				res.Header.Get("x-amz-bucket-region"),
+				lat,
+				info,
+			)
+			r.Prefixes = ps
+			conf.send(r)
+			return
+		}
+		if nil != info && 0 != len(info.documents) {
+			conf.slog.Printf(
+				"[%v] Forbidden (%v)%v%v%v",
+				n,
+				bucketURL,
+				took,
+				conf.via(cand),
+				info,
+			)
+		} else if !conf.ignore.Suppress(IgnoreForbidden) {
+			log.Printf(
+				"[%v] Forbidden (%v)%v%v",
+				n,
+				bucketURL,
+				took,
+				conf.via(cand),
+			)
+		} else {
+			return
+		}
+		conf.emit(
+			cand,
+			bucketURL,
+			StatusForbidden,
+			res,
+			res.Header.Get("x-amz-bucket-region"),
+			lat,
+			info,
+		)
+		return
+	case 404: /* Not a bucket */
+		conf.negative(n)
+		if conf.nonBuckets && !conf.ignore.Suppress(IgnoreNotBucket) {
+			log.Printf(
+				"[%v] Not a bucket%v%v",
+				n,
+				took,
+				conf.via(cand),
+			)
+			conf.emit(
+				cand,
+				bucketURL,
+				StatusNotBucket,
+				res,
+				region,
+				lat,
+				nil,
+			)
 		}
-	}
-}
-
-/* checker checks if the domain names sent on namech are public s3 buckets.
-Requests to see if the domain is an S3 bucket are made with c.  If nonBuckets
-is true, names which aren't buckets are printed. */
-func checker(
-	bucketch <-chan string,
-	c *http.Client,
-	wg *sync.WaitGroup,
-	slog *log.Logger,
-	nonBuckets bool,
-	ignoreNotAllowed bool,
-) {
-	defer wg.Done()
-	for bucket := range bucketch {
-		/* Check each name */
-		check(
-			bucket,
-			"",
-			c,
-			MAXRECURSION,
-			slog,
-			nonBuckets,
-			ignoreNotAllowed,
+		return
+	default: /* Response we've not seen before */
+		conf.unexpected.Add(fmt.Sprint(res.StatusCode))
+		if !conf.reportUnexpected {
+			log.Printf(
+				"[%v] Unexpected response to bucket check "+
+					"at %v: %v",
+				n,
+				req.URL,
+				res.Status,
+			)
+			return
+		}
+		conf.slog.Printf(
+			"[%v] Unexpected response (%v): %v%v%v",
+			n,
+			bucketURL,
+			res.Status,
+			took,
+			conf.via(cand),
 		)
+		r := conf.result(
+			cand,
+			bucketURL,
+			StatusUnexpected,
+			res,
+			region,
+			lat,
+			nil,
+		)
+		r.StatusLine = res.Status
+		r.Body = string(body)
+		conf.send(r)
+		return
 	}
 }
 
-/* check checks if n is a domain pointing to a publically-accessible s3 bucket,
-using c to make requests to see if the bucket is public.  rem controlls how
-many recurions remain before we give up.  If nonBuckets is true, names which
-aren't buckets are printed.  If ignoreNotALlowed is true, HTTP403's are
-silently ignored. */
-func check(
-	n string,
-	region string,
-	c *http.Client,
-	rem uint,
-	slog *log.Logger,
-	nonBuckets bool,
-	ignoreNotAllowed bool,
-) {
-	/* Make sure we're allowed to recurse */
-	if 0 == rem {
-		log.Printf("[%v] Too many attempts", n)
-		return
+/* confirmCheck repeats orig, the request which found the bucket cand at
+bucketURL with the HTTP status code status, using conf.confirm.  It returns
+true if the second request got the same status, or if conf.confirm is nil. */
+func confirmCheck(
+	cand candidate,
+	orig *http.Request,
+	bucketURL string,
+	status int,
+	worker uint,
+	conf *checkConfig,
+) bool {
+	if nil == conf.confirm {
+		return true
 	}
+	n := cand.name
 
-	/* Make sure the region starts with a -, if needed */
-	if "" != region && !strings.HasPrefix(region, "-") {
-		region = "-" + region
+	/* Roll the request again */
+	req, err := http.NewRequest("GET", orig.URL.String(), nil)
+	if nil != err {
+		elog.Printf(
+			"[%v] Unable to make confirmation request: %v",
+			n,
+			err,
+		)
+		return false
 	}
+	req.Host = orig.Host
+	req = req.WithContext(orig.Context())
 
-	/* Check if it's an S3 bucket */
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf(S3URL, region),
-		nil,
-	)
+	/* See if we get the same answer */
+	if !conf.wait(req.Context()) {
+		return false
+	}
+	start := time.Now()
+	res, err := conf.confirm.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add("confirm")
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
 	if nil != err {
-		log.Printf("[%v] Bucket name creates invalid URL: %v", n, err)
-		return
+		if nil == req.Context().Err() {
+			elog.Printf("[%v] Unable to confirm bucket: %v", n, err)
+		}
+		return false
+	}
+	res.Body.Close()
+	if status == res.StatusCode {
+		return true
 	}
-	req.Host = n
-	res, err := c.Do(req)
+	log.Printf(
+		"[%v] Not confirmed (%v): got %v, then %v",
+		n,
+		bucketURL,
+		status,
+		res.Status,
+	)
+	return false
+}
 
-	/* URL for bucket */
-	bucketURL := req.URL.String() + "/" + n
+/* headRegion finds the region of the bucket cand with an anonymous HEAD
+request to ep's global URL, which returns the bucket's region even if it's
+forbidden.  It returns false if there's no need to look further: the name
+isn't a bucket, which is reported as check would, or the bucket's in a region
+which isn't allowed.  If the bucket's region can't be found, headRegion
+returns the empty string and true and leaves it to check to work out. */
+func headRegion(
+	ctx context.Context,
+	cand candidate,
+	ep endpoints,
+	worker uint,
+	conf *checkConfig,
+) (string, bool) {
+	n := cand.name
+
+	/* Ask where it is */
+	req, err := http.NewRequest("HEAD", ep.url(n, ""), nil)
+	if nil != err {
+		elog.Printf("[%v] Bucket name creates invalid URL: %v", n, err)
+		return "", false
+	}
+	if ep.inHost() {
+		req.Host = n
+	}
+	req = req.WithContext(ctx)
+	if !conf.wait(ctx) {
+		return "", false
+	}
+	start := time.Now()
+	res, err := conf.client.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add(ep.name)
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
+	spanRequest(ctx, ep.name, req, res, err, lat)
+	bucketURL := req.URL.String()
+	if ep.inHost() {
+		bucketURL += "/" + n
+	}
 
-	/* Handle request errors */
+	/* Errors are check's problem, it'll retry if need be */
 	if nil != err {
-		var m string
-		/* Try again if we EOF or no route to host */
-		if strings.HasSuffix(err.Error(), ": EOF") {
-			m = fmt.Sprintf("[%v] Retrying due to EOF", bucketURL)
-		} else if strings.HasSuffix(err.Error(), "no route to host") {
-			m = fmt.Sprintf(
-				"[%v] Retrying due to route error",
-				bucketURL,
+		return "", nil == ctx.Err()
+	}
+	res.Body.Close()
+
+	/* Not a bucket needn't be asked again */
+	if http.StatusNotFound == ep.status(res.StatusCode) {
+		conf.negative(n)
+		if conf.nonBuckets && !conf.ignore.Suppress(IgnoreNotBucket) {
+			log.Printf(
+				"[%v] Not a bucket%v%v",
+				n,
+				conf.latencies.Describe(lat),
+				conf.via(cand),
 			)
-		} else if strings.HasSuffix(
-			err.Error(),
-			": TLS handshake timeout",
-		) {
-			m = fmt.Sprintf(
-				"[%v] Retrying due to TLS handshake timeout",
+			conf.emit(
+				cand,
 				bucketURL,
+				StatusNotBucket,
+				res,
+				"",
+				lat,
+				nil,
 			)
-		} else {
-			/* Any other error is probably fatal for this name */
-			log.Printf("[%v] Bucket check error: %v", n, err)
-			return
 		}
-		/* Wait for temporary problems to resolve */
-		log.Printf("%v", m)
-		time.Sleep(RETRYWAIT)
-		check(
-			n,
-			region,
-			c,
-			rem-1,
-			slog,
-			nonBuckets,
-			ignoreNotAllowed,
-		)
-		return
+		return "", false
 	}
-	res.Body.Close()
 
-	/* TODO: Make sure it doesn't require name.amazon syntax */
+	/* Any other answer should say where the bucket is */
+	region := res.Header.Get("x-amz-bucket-region")
+	if "" == region {
+		return "", true
+	}
+	if !conf.regionAllowed(cand, bucketURL, region, res, lat) {
+		return "", false
+	}
+	conf.json.Region(cand, bucketURL, region)
+	return region, true
+}
 
-	/* See what happens */
+/* retryReason returns why a request which failed with err is worth retrying,
+or the empty string if it isn't.  Errors from traces being replayed are only
+strings, so the error's text is checked as well. */
+func retryReason(err error) string {
+	s := err.Error()
+	switch {
+	case errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		strings.HasSuffix(s, ": EOF"):
+		return "EOF"
+	case errors.Is(err, syscall.EHOSTUNREACH),
+		strings.HasSuffix(s, "no route to host"):
+		return "route error"
+	case errors.Is(err, syscall.ECONNRESET),
+		strings.HasSuffix(s, "connection reset by peer"):
+		return "connection reset"
+	case strings.HasSuffix(s, ": TLS handshake timeout"):
+		return "TLS handshake timeout"
+	case isTimeout(err),
+		strings.HasSuffix(s, "(Client.Timeout exceeded while "+
+			"awaiting headers)"):
+		return "timeout"
+	}
+	return ""
+}
+
+/* isTimeout returns true if err is a network timeout. */
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+/* offS3Redirect returns where res, the response to req, redirects if it's a
+redirect to somewhere other than AWS. */
+func offS3Redirect(req *http.Request, res *http.Response) (*url.URL, bool) {
 	switch res.StatusCode {
-	case 200: /* Public bucket */
-		slog.Printf("[%v] Public bucket: %v", n, bucketURL)
-	case 307: /* Redirect, it's probably an S3 bucket in another region */
-		region := res.Header.Get("x-amz-bucket-region")
-		/* We shouldn't be redirected to the default region */
-		if "" == region || "us-east-1" == region {
-			log.Printf(
-				"[%v] Unexpected redirect to %q",
-				n,
-				res.Header.Get("location"),
-			)
-		}
-		/* Check with new region in URL */
-		check(n, region, c, rem-1, slog, nonBuckets, ignoreNotAllowed)
-	case 400: /* Bad request */
-		log.Printf("[%v] Bad request (%v)", n, bucketURL)
-		return
-	case 403: /* Bucket, but forbidden */
-		if !ignoreNotAllowed {
-			log.Printf("[%v] Forbidden (%v)", n, bucketURL)
-		}
-		return
-	case 404: /* Not a bucket */
-		if nonBuckets {
-			log.Printf("[%v] Not a bucket", n)
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusSeeOther,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+	default:
+		return nil, false
+	}
+	loc := res.Header.Get("Location")
+	if "" == loc {
+		return nil, false
+	}
+	u, err := req.URL.Parse(loc)
+	if nil != err {
+		return nil, false
+	}
+	h := strings.ToLower(u.Hostname())
+	for _, d := range []string{"amazonaws.com", "amazonaws.com.cn"} {
+		if d == h || strings.HasSuffix(h, "."+d) {
+			return nil, false
 		}
-		return
-	default: /* Response we've not seen before */
-		log.Printf(
-			"[%v] Unexpected response to bucket check at %v: %v",
+	}
+	if S3PATHURL == u.String() {
+		return nil, false
+	}
+	return u, true
+}
+
+/* checkRequesterPays retries a forbidden bucket cand as a requester-pays
+bucket, using the same URL and Host as orig and conf.creds to sign a request
+for the given region.  It returns true if the bucket, at bucketURL, was
+readable. */
+func checkRequesterPays(
+	cand candidate,
+	orig *http.Request,
+	bucketURL string,
+	region string,
+	worker uint,
+	conf *checkConfig,
+) bool {
+	n := cand.name
+
+	/* The default region isn't always sent */
+	if "" == region {
+		region = "us-east-1"
+	}
+
+	/* Roll the request, paying this time */
+	req, err := http.NewRequest("GET", orig.URL.String(), nil)
+	if nil != err {
+		elog.Printf(
+			"[%v] Unable to make requester-pays request: %v",
 			n,
-			req.URL,
-			res.Status,
+			err,
 		)
-		return
+		return false
+	}
+	req.Host = orig.Host
+	req = req.WithContext(orig.Context())
+	req.Header.Set("X-Amz-Request-Payer", "requester")
+
+	/* See if we can read it, signing once it's our turn so the
+	signature's fresh */
+	if !conf.wait(req.Context()) {
+		return false
+	}
+	conf.creds.sign(req, region, nil)
+	start := time.Now()
+	res, err := conf.client.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add("requester-pays")
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
+	if nil != err {
+		if nil == req.Context().Err() {
+			elog.Printf(
+				"[%v] Requester-pays check error: %v",
+				n,
+				err,
+			)
+		}
+		return false
+	}
+	res.Body.Close()
+	if http.StatusOK != res.StatusCode {
+		return false
 	}
+	conf.slog.Printf(
+		"[%v] Requester-pays bucket: %v%v%v",
+		n,
+		bucketURL,
+		conf.latencies.Describe(lat),
+		conf.via(cand),
+	)
+	conf.emit(
+		cand,
+		bucketURL,
+		StatusRequesterPays,
+		res,
+		region,
+		lat,
+		nil,
+	)
+	return true
+}
+
+/* nameConfig holds the settings and state used to turn names into possible
+bucket names. */
+type nameConfig struct {
+	/* tags are added to each name */
+	tags []string
+
+	/* dates are appended to each name */
+	dates []string
+
+	/* seen holds the names we've already processed */
+	seen nameSet
+
+	/* rules determines which names are allowed */
+	rules namingRules
+
+	/* domains is the set of registrable domains seen */
+	domains map[string]struct{}
+
+	/* withWWW causes a www-prefixed version of each domain name to be
+	processed as well */
+	withWWW bool
+
+	/* maxNames, if not 0, is the number of distinct names after which
+	no more names will be processed */
+	maxNames uint
+
+	/* found notes which names were found as subdomains, and how */
+	found *nameSources
+
+	/* hits notes which inputs have had a public bucket found */
+	hits *firstHits
+
+	/* suffixes works out domains' public suffixes */
+	suffixes *suffixList
+
+	/* keepOriginal causes input names to always be checked, even if
+	they've been seen before, and changes made to them to be logged */
+	keepOriginal bool
+
+	/* totals counts the names processed */
+	totals *runTotals
 }
 
 /* processNames turns the names on namech into a load of possible bucket names
-which are sent to bucketch.  The certificate transparency logs will be queried
-for subdomains if useCTL is true. */
+which are sent to bucketch, according to conf.  The registrable domain of
+every domain-style name is added to conf.domains.  The certificate
+transparency logs will be queried for subdomains if useCTL is true. */
 func processNames(
-	bucketch chan<- string,
+	bucketch chan<- candidate,
 	namech <-chan string,
-	tags []string,
-	seen *lru.Cache,
+	conf *nameConfig,
 	useCTL bool,
 ) {
 	defer close(bucketch)
 
+	/* Distinct names processed, if we're limiting them */
+	var processed map[string]struct{}
+	if 0 != conf.maxNames {
+		processed = make(map[string]struct{})
+	}
+
 	/* Check each name sent to us, adding interesting bits and paring down
 	long domains. */
 	for name := range namech {
@@ -475,74 +3295,184 @@ func processNames(
 		if "" == name || strings.HasPrefix(name, "#") {
 			continue
 		}
+		conf.totals.Name()
 
-		/* Names without a dot aren't DNS names, no need to split */
-		if !strings.Contains(name, ".") {
-			processName(bucketch, seen, name, tags)
-			continue
+		/* Names are as given unless a subdomain source found them */
+		src := conf.found.Take(name)
+		if "" == src {
+			src = SourceLiteral
 		}
+		processInput(bucketch, name, src, conf)
 
-		/* We likely have a domain name (or something like one).
-		Process it and all its parents until but not including the
-		public suffix. */
-		ps, _ := publicsuffix.PublicSuffix(name)
-
-		/* Process the name and its parents */
-		for name != ps {
-			/* Get subdomains */
-			processName(bucketch, seen, name, tags)
-			/* Split leftmost domain off */
-			parts := strings.SplitN(name, ".", 2)
-			if 2 != len(parts) {
-				log.Panicf("unable to get parent of %q", parts)
-			}
-			/* Process bare label, as well */
-			processName(bucketch, seen, parts[0], tags)
-			/* Process parent next time */
-			name = parts[1]
-			if "" == name {
-				return
+		/* Stop if we've had enough, but don't leave the senders
+		hanging. */
+		if nil == processed {
+			continue
+		}
+		processed[name] = struct{}{}
+		if uint(len(processed)) < conf.maxNames {
+			continue
+		}
+		log.Printf(
+			"Processed %v distinct names, ignoring the rest",
+			len(processed),
+		)
+		go func() {
+			for range namech {
 			}
+		}()
+		return
+	}
+}
+
+/* rawNames sends the names on namech to bucketch as-is, as long as they're
+allowed by rules, counting them in totals.  Unlike processNames, no other
+names are generated and duplicates aren't skipped.  It closes bucketch on
+return. */
+func rawNames(
+	bucketch chan<- candidate,
+	namech <-chan string,
+	rules namingRules,
+	totals *runTotals,
+) {
+	defer close(bucketch)
+	for name := range namech {
+		/* Skip empty names and names which look like comments. */
+		name := strings.TrimSpace(name)
+		if "" == name || strings.HasPrefix(name, "#") {
+			continue
+		}
+		totals.Name()
+		if p := rules.problem(name); "" != p {
+			log.Printf("[%v] Invalid name: %v", name, p)
+			continue
+		}
+		bucketch <- candidate{
+			name:   name,
+			source: SourceLiteral,
+			input:  name,
 		}
 	}
 }
 
-/* processName appends and prepends various tags to the name and changes dots
-to hyphens.  The resulting names are sent to bucketch. */
-func processName(
-	bucketch chan<- string,
-	seen *lru.Cache,
+/* processInput sends the possible bucket names for the input name, which
+came from src, to bucketch.  Domain names are split and their parents are
+processed as well. */
+func processInput(
+	bucketch chan<- candidate,
 	name string,
-	tags []string,
+	src string,
+	conf *nameConfig,
 ) {
-	/* Sanitize name */
-	name = strings.Map(func(r rune) rune {
-		if !strings.ContainsRune(NAMECHARS, r) {
-			return -1
+	span := startInputSpan(name)
+	defer span.End()
+	in := candidate{input: name, span: span.SpanContext()}
+
+	/* Names without a dot aren't DNS names, no need to split */
+	if !strings.Contains(name, ".") {
+		processName(bucketch, in.derive(name, src), conf)
+		return
+	}
+
+	/* Try with a www., if we're meant to */
+	if conf.withWWW && !strings.HasPrefix(name, "www.") {
+		processName(
+			bucketch,
+			in.derive("www."+name, SourceWWWPrefix),
+			conf,
+		)
+	}
+
+	/* Note the registrable domain, for the summary */
+	if rd, err := conf.suffixes.EffectiveTLDPlusOne(name); nil == err {
+		conf.domains[rd] = struct{}{}
+	}
+
+	/* We likely have a domain name (or something like one).  Process it
+	and all its parents until but not including the public suffix. */
+	ps := conf.suffixes.PublicSuffix(name)
+
+	/* Process the name and its parents */
+	for name != ps {
+		/* No point in going on if we've found something */
+		if conf.hits.Hit(in.input) {
+			return
+		}
+		/* Get subdomains */
+		processName(bucketch, in.derive(name, src), conf)
+		/* Split leftmost domain off */
+		parts := strings.SplitN(name, ".", 2)
+		if 2 != len(parts) {
+			log.Panicf("unable to get parent of %q", parts)
+		}
+		/* Process bare label, as well */
+		processName(
+			bucketch,
+			in.derive(parts[0], SourceParentLabel),
+			conf,
+		)
+		/* Process parent next time */
+		name, src = parts[1], SourceParentLabel
+		if "" == name {
+			return
 		}
-		return r
-	}, name)
+	}
+}
 
-	/* Make sure name doesn't start or end with a . */
-	name = strings.Trim(name, ".")
+/* processName appends and prepends conf's tags to the candidate c's name and
+changes dots to hyphens.  The resulting names which are allowed by conf's
+rules are sent to bucketch, along with how they were generated.  Once a
+public bucket's been found for c's input, no more names are sent.
+
+The name is first sanitized and has leading and trailing dots removed.  Names
+which are empty, have already been seen, or have a label longer than allowed
+are skipped.  For a name n and every tag t, the candidates are then
+
+	n
+	tn, nt, t.n, n.t, t-n, n-t
+
+and, for every one of conf's dates d, nd and n-d, and each of those with its
+dots changed to hyphens, its hyphens changed to dots, and its dots and hyphens
+swapped, with runs of dots compressed to a single dot.  Duplicates are sent
+only once.  The name itself has c's source, tn, t.n, and t-n are
+SourceTagPrefix, the rest of the tagged names are SourceTagSuffix, names with
+dates are SourceDateSuffix, and names with dots or hyphens changed are
+SourceDotSwap. */
+func processName(bucketch chan<- candidate, c candidate, conf *nameConfig) {
+	/* Sanitize name, making sure it doesn't start or end with a . */
+	name := conf.rules.normalize(c.name)
+
+	/* Tell the user if we're not checking exactly what they gave us */
+	orig := conf.keepOriginal && SourceLiteral == c.source
+	if orig && name != c.name {
+		if "" == name {
+			log.Printf("[%v] Nothing left to check", c.name)
+		} else {
+			log.Printf("[%v] Normalized to %v", c.name, name)
+		}
+	}
 
 	/* Don't use empty names */
 	if "" == name {
 		return
 	}
 
-	/* If we've seen the name, don't try again */
-	if _, ok := seen.Get(name); ok {
+	/* If we've seen the name, don't try again, unless it's an input we
+	always want checked.  Tags only get added the first time, though. */
+	if conf.seen.Seen(name) {
+		if orig && conf.rules.valid(name) {
+			bucketch <- c.derive(name, c.source)
+		}
 		return
 	}
 
 	/* Note we've seen it, to prevent rechecking */
-	seen.Add(name, nil)
+	conf.seen.Add(name)
 
 	/* If any of the labels are too long, don't try */
 	parts := strings.Split(name, ".")
 	for _, part := range parts {
-		if MAXLABELLEN < len(part) {
+		if conf.rules.maxLabelLen < len(part) {
 			log.Printf(
 				"[%v] Invalid name: label %q too long",
 				name,
@@ -553,37 +3483,85 @@ func processName(
 	}
 
 	/* Send name, as-is */
-	sendWithDotsAndHyphensChanged(bucketch, []string{name})
+	sendWithDotsAndHyphensChanged(
+		bucketch,
+		[]candidate{c.derive(name, c.source)},
+		conf.rules,
+	)
 
 	/* Add tags, send out */
-	for _, tag := range tags {
-		sendWithDotsAndHyphensChanged(bucketch, []string{
-			tag + name,
-			name + tag,
-			tag + "." + name,
-			name + "." + tag,
-			tag + "-" + name,
-			name + "-" + tag,
-		})
+	for _, tag := range conf.tags {
+		if conf.hits.Hit(c.input) {
+			return
+		}
+		sendWithDotsAndHyphensChanged(bucketch, []candidate{
+			c.derive(tag+name, SourceTagPrefix),
+			c.derive(name+tag, SourceTagSuffix),
+			c.derive(tag+"."+name, SourceTagPrefix),
+			c.derive(name+"."+tag, SourceTagSuffix),
+			c.derive(tag+"-"+name, SourceTagPrefix),
+			c.derive(name+"-"+tag, SourceTagSuffix),
+		}, conf.rules)
+	}
+
+	/* Add dates, send out */
+	for _, date := range conf.dates {
+		if conf.hits.Hit(c.input) {
+			return
+		}
+		sendWithDotsAndHyphensChanged(bucketch, []candidate{
+			c.derive(name+date, SourceDateSuffix),
+			c.derive(name+"-"+date, SourceDateSuffix),
+		}, conf.rules)
 	}
 }
 
-/* sendWithDotsAndHyphensChanged sends every string in ns to c with several
+/* sendWithDotsAndHyphensChanged sends every candidate in ns to c with several
 combinations of changing dots to dashes and vice-versa.  No duplicates will be
-sent. */
-func sendWithDotsAndHyphensChanged(c chan<- string, ns []string) {
-	m := map[string]struct{}{} /* Deduper */
+sent, nor will names not allowed by rules.  At most four names are sent for
+each candidate in ns.  Changed names are sent as SourceDotSwap unless they
+are the same as one of the unchanged names.  The candidates in ns must all
+have the same input. */
+func sendWithDotsAndHyphensChanged(
+	c chan<- candidate,
+	ns []candidate,
+	rules namingRules,
+) {
+	/* Names without dots or hyphens have nothing to change, which is
+	common for bare labels. */
+	if 1 == len(ns) && !strings.ContainsAny(ns[0].name, ".-") {
+		if rules.valid(ns[0].name) {
+			c <- ns[0]
+		}
+		return
+	}
 
-	/* Add all combinations to m */
+	/* Deduper, with runs of .. compressed as names are added.  The first
+	source for a name wins. */
+	m := make(map[string]string, 4*len(ns))
+	add := func(k, src string) {
+		for strings.Contains(k, "..") {
+			k = strings.Replace(k, "..", ".", -1)
+		}
+		if _, ok := m[k]; !ok {
+			m[k] = src
+		}
+	}
+
+	/* The strings themselves */
 	for _, n := range ns {
-		/* The string itself */
-		m[n] = struct{}{}
+		add(n.name, n.source)
+	}
+
+	/* Add all combinations to m */
+	for _, cand := range ns {
+		n := cand.name
 		/* With hyphens */
-		m[strings.Replace(n, ".", "-", -1)] = struct{}{}
+		add(strings.Replace(n, ".", "-", -1), SourceDotSwap)
 		/* With dots */
-		m[strings.Replace(n, "-", ".", -1)] = struct{}{}
+		add(strings.Replace(n, "-", ".", -1), SourceDotSwap)
 		/* Switching them */
-		m[strings.Map(func(r rune) rune {
+		add(strings.Map(func(r rune) rune {
 			switch r {
 			case '.':
 				return '-'
@@ -592,31 +3570,23 @@ func sendWithDotsAndHyphensChanged(c chan<- string, ns []string) {
 			default:
 				return r
 			}
-		}, n)] = struct{}{}
+		}, n), SourceDotSwap)
 	}
 
-	/* Compress runs of .. */
-	for k := range m {
-		if !strings.Contains(k, "..") {
+	/* Send them out */
+	for k, src := range m {
+		if !rules.valid(k) {
 			continue
 		}
-		delete(m, k)
-		for strings.Contains(k, "..") {
-			k = strings.Replace(k, "..", ".", -1)
-		}
-		m[k] = struct{}{}
-	}
-
-	/* Send them out */
-	for k := range m {
-		c <- k
+		c <- ns[0].derive(k, src)
 	}
 }
 
 /* getTags returns a slice of tags to use.  If fn is "no", it returns an empty
 slice.  If fn is the empty string, it returns tags from TAGLIST.  Otherwise
 fn is treated as a filename and tags are read from the file, one per line.
-Blank lines and comments are skipped. */
+Blank lines and comments are skipped.  Tags starting with a ! are priority
+tags, and are returned, without the !, before the rest. */
 func getTags(fn string) ([]string, error) {
 	/* No means no tags */
 	if "no" == fn {
@@ -633,9 +3603,10 @@ func getTags(fn string) ([]string, error) {
 	if nil != err {
 		return nil, err
 	}
-	/* Read each line, appending it to o if it's a tag */
+	defer f.Close()
+	/* Read each line, appending it to o or p if it's a tag */
 	s := bufio.NewScanner(f)
-	var o []string
+	var o, p []string
 	for s.Scan() {
 		/* Line from file */
 		l := strings.TrimSpace(s.Text())
@@ -643,91 +3614,104 @@ func getTags(fn string) ([]string, error) {
 		if "" == l || strings.HasPrefix(l, "#") {
 			continue
 		}
+		/* Priority tags go first */
+		if strings.HasPrefix(l, "!") {
+			if l = strings.TrimSpace(l[1:]); "" != l {
+				p = append(p, l)
+			}
+			continue
+		}
 		o = append(o, l)
 	}
 	if err := s.Err(); nil != err {
 		return nil, err
 	}
-	return o, nil
+	return append(p, o...), nil
 }
 
-/* queryCTL queries the CTL for subdomains of n.  It returns an empty slice and
-no error if none were found. */
-func queryCTL(n string) ([]string, error) {
-	/* Get JSON with more domains */
-	res, err := http.Get(fmt.Sprintf(CTLURL, url.QueryEscape(n)))
-	if nil != err {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	/* 404's mean no names */
-	if http.StatusNotFound == res.StatusCode {
-		return []string{}, nil
+/* prioritizeTags returns tags with the tags in priority moved or added to the
+front, in the order in which they appear in priority. */
+func prioritizeTags(priority, tags []string) []string {
+	o := make([]string, 0, len(priority)+len(tags))
+	seen := make(map[string]struct{})
+	for _, ts := range [][]string{priority, tags} {
+		for _, t := range ts {
+			if _, ok := seen[t]; ok {
+				continue
+			}
+			seen[t] = struct{}{}
+			o = append(o, t)
+		}
 	}
+	return o
+}
 
-	/* Put commas between bits, turn into a list */
-	b, err := ioutil.ReadAll(res.Body)
+/* readKnown reads the names of known public buckets from the file named fn.
+Each line should either be a JSON-encoded Result, in which case only public
+and requester-pays buckets are returned, or a bucket name.  Blank lines and
+comments are skipped. */
+func readKnown(fn string) (map[string]struct{}, error) {
+	f, err := os.Open(fn)
 	if nil != err {
 		return nil, err
 	}
+	defer f.Close()
 
-	/* Unmarshal JSON */
-	var cs []struct {
-		Name string `json:"name_value"`
-	}
-	if err := json.Unmarshal(
-		bytes.Join([][]byte{
-			[]byte("["),
-			bytes.Replace(b, []byte("}{"), []byte("},{"), -1),
-			[]byte("]"),
-		}, []byte{}),
-		&cs,
-	); nil != err {
-		return nil, err
-	}
-
-	/* Dedupe and return names */
-	m := make(map[string]struct{})
-	for _, c := range cs {
-		if "" == c.Name {
+	known := make(map[string]struct{})
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		/* Skip blank lines and comments */
+		if "" == l || strings.HasPrefix(l, "#") {
+			continue
+		}
+		/* Plain names are easy */
+		if !strings.HasPrefix(l, "{") {
+			known[l] = struct{}{}
 			continue
 		}
-		m[c.Name] = struct{}{}
+		var r Result
+		if err := json.Unmarshal([]byte(l), &r); nil != err {
+			return nil, err
+		}
+		switch r.Status {
+		case StatusPublic, StatusRequesterPays:
+			known[r.Name] = struct{}{}
+		}
 	}
-	ns := make([]string, 0, len(m))
-	for k := range m {
-		ns = append(ns, k)
+	if err := s.Err(); nil != err {
+		return nil, err
 	}
-	return ns, nil
+	return known, nil
 }
 
-/* getCTLNames sends to out anything on ns, plus any names of subdomains of
-names on ns if the name contains a dot. */
-func getCTLNames(out chan<- string, ns <-chan string) {
-	defer close(out)
-	for n := range ns {
-		/* Send out original name */
-		out <- n
-		/* Skip non-domains */
-		if !strings.Contains(n, ".") {
-			continue
-		}
-		/* Send out all subdomains as well */
-		ss, err := queryCTL(n)
-		if nil != err {
-			log.Printf(
-				"Unable to query crt.sh for subdomains of "+
-					"%v: %v",
-				n,
-				err,
-			)
-			continue
-		}
-		for _, s := range ss {
-			out <- s
+/* writeDomains writes the keys of domains to the file named fn, one per line,
+in sorted order.  The file is truncated if it exists. */
+func writeDomains(fn string, domains map[string]struct{}) error {
+	/* Sort the domains, for easy reading */
+	ds := make([]string, 0, len(domains))
+	for d := range domains {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+
+	/* Write them to the file */
+	f, err := os.Create(fn)
+	if nil != err {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, d := range ds {
+		if _, err := fmt.Fprintln(w, d); nil != err {
+			f.Close()
+			return err
 		}
 	}
+	if err := w.Flush(); nil != err {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 // TAGLIST contains the default list of tags to try to prepend and append to
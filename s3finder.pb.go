@@ -0,0 +1,396 @@
+// s3finder.proto
+// gRPC interface for checking names
+// By J. Stuart McMurray
+// Created 20261014
+// Last Modified 20261014
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: s3finder.proto
+
+package main
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// CheckNamesRequest is a name to check.
+type CheckNamesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckNamesRequest) Reset() {
+	*x = CheckNamesRequest{}
+	mi := &file_s3finder_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckNamesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckNamesRequest) ProtoMessage() {}
+
+func (x *CheckNamesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_s3finder_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckNamesRequest.ProtoReflect.Descriptor instead.
+func (*CheckNamesRequest) Descriptor() ([]byte, []int) {
+	return file_s3finder_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckNamesRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// CheckResult describes the outcome of checking a name.  It has the same
+// fields as the JSON results sent to -socket clients.
+type CheckResult struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	BucketUrl        string                 `protobuf:"bytes,2,opt,name=bucket_url,json=bucketUrl,proto3" json:"bucket_url,omitempty"`
+	Status           string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	HttpStatus       int32                  `protobuf:"varint,4,opt,name=http_status,json=httpStatus,proto3" json:"http_status,omitempty"`
+	Region           string                 `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
+	Timestamp        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	LatencyMs        float64                `protobuf:"fixed64,7,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	Source           string                 `protobuf:"bytes,8,opt,name=source,proto3" json:"source,omitempty"`
+	Versioning       string                 `protobuf:"bytes,9,opt,name=versioning,proto3" json:"versioning,omitempty"`
+	LifecycleRules   *int32                 `protobuf:"varint,10,opt,name=lifecycle_rules,json=lifecycleRules,proto3,oneof" json:"lifecycle_rules,omitempty"`
+	Headers          map[string]string      `protobuf:"bytes,11,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	StatusLine       string                 `protobuf:"bytes,12,opt,name=status_line,json=statusLine,proto3" json:"status_line,omitempty"`
+	Body             string                 `protobuf:"bytes,13,opt,name=body,proto3" json:"body,omitempty"`
+	Documents        map[string]string      `protobuf:"bytes,14,rep,name=documents,proto3" json:"documents,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	RedirectUrl      string                 `protobuf:"bytes,15,opt,name=redirect_url,json=redirectUrl,proto3" json:"redirect_url,omitempty"`
+	RedirectHost     string                 `protobuf:"bytes,16,opt,name=redirect_host,json=redirectHost,proto3" json:"redirect_host,omitempty"`
+	ReadablePrefixes []string               `protobuf:"bytes,17,rep,name=readable_prefixes,json=readablePrefixes,proto3" json:"readable_prefixes,omitempty"`
+	OwnerId          string                 `protobuf:"bytes,18,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	OwnerName        string                 `protobuf:"bytes,19,opt,name=owner_name,json=ownerName,proto3" json:"owner_name,omitempty"`
+	Severity         string                 `protobuf:"bytes,20,opt,name=severity,proto3" json:"severity,omitempty"`
+	RunId            string                 `protobuf:"bytes,21,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	RunStart         *timestamppb.Timestamp `protobuf:"bytes,22,opt,name=run_start,json=runStart,proto3" json:"run_start,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CheckResult) Reset() {
+	*x = CheckResult{}
+	mi := &file_s3finder_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResult) ProtoMessage() {}
+
+func (x *CheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_s3finder_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResult.ProtoReflect.Descriptor instead.
+func (*CheckResult) Descriptor() ([]byte, []int) {
+	return file_s3finder_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CheckResult) GetBucketUrl() string {
+	if x != nil {
+		return x.BucketUrl
+	}
+	return ""
+}
+
+func (x *CheckResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CheckResult) GetHttpStatus() int32 {
+	if x != nil {
+		return x.HttpStatus
+	}
+	return 0
+}
+
+func (x *CheckResult) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *CheckResult) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *CheckResult) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *CheckResult) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *CheckResult) GetVersioning() string {
+	if x != nil {
+		return x.Versioning
+	}
+	return ""
+}
+
+func (x *CheckResult) GetLifecycleRules() int32 {
+	if x != nil && x.LifecycleRules != nil {
+		return *x.LifecycleRules
+	}
+	return 0
+}
+
+func (x *CheckResult) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *CheckResult) GetStatusLine() string {
+	if x != nil {
+		return x.StatusLine
+	}
+	return ""
+}
+
+func (x *CheckResult) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *CheckResult) GetDocuments() map[string]string {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+func (x *CheckResult) GetRedirectUrl() string {
+	if x != nil {
+		return x.RedirectUrl
+	}
+	return ""
+}
+
+func (x *CheckResult) GetRedirectHost() string {
+	if x != nil {
+		return x.RedirectHost
+	}
+	return ""
+}
+
+func (x *CheckResult) GetReadablePrefixes() []string {
+	if x != nil {
+		return x.ReadablePrefixes
+	}
+	return nil
+}
+
+func (x *CheckResult) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *CheckResult) GetOwnerName() string {
+	if x != nil {
+		return x.OwnerName
+	}
+	return ""
+}
+
+func (x *CheckResult) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *CheckResult) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *CheckResult) GetRunStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RunStart
+	}
+	return nil
+}
+
+var File_s3finder_proto protoreflect.FileDescriptor
+
+const file_s3finder_proto_rawDesc = "" +
+	"\n" +
+	"\x0es3finder.proto\x12\bs3finder\x1a\x1fgoogle/protobuf/timestamp.proto\"'\n" +
+	"\x11CheckNamesRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xb0\a\n" +
+	"\vCheckResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"bucket_url\x18\x02 \x01(\tR\tbucketUrl\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1f\n" +
+	"\vhttp_status\x18\x04 \x01(\x05R\n" +
+	"httpStatus\x12\x16\n" +
+	"\x06region\x18\x05 \x01(\tR\x06region\x128\n" +
+	"\ttimestamp\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\a \x01(\x01R\tlatencyMs\x12\x16\n" +
+	"\x06source\x18\b \x01(\tR\x06source\x12\x1e\n" +
+	"\n" +
+	"versioning\x18\t \x01(\tR\n" +
+	"versioning\x12,\n" +
+	"\x0flifecycle_rules\x18\n" +
+	" \x01(\x05H\x00R\x0elifecycleRules\x88\x01\x01\x12<\n" +
+	"\aheaders\x18\v \x03(\v2\".s3finder.CheckResult.HeadersEntryR\aheaders\x12\x1f\n" +
+	"\vstatus_line\x18\f \x01(\tR\n" +
+	"statusLine\x12\x12\n" +
+	"\x04body\x18\r \x01(\tR\x04body\x12B\n" +
+	"\tdocuments\x18\x0e \x03(\v2$.s3finder.CheckResult.DocumentsEntryR\tdocuments\x12!\n" +
+	"\fredirect_url\x18\x0f \x01(\tR\vredirectUrl\x12#\n" +
+	"\rredirect_host\x18\x10 \x01(\tR\fredirectHost\x12+\n" +
+	"\x11readable_prefixes\x18\x11 \x03(\tR\x10readablePrefixes\x12\x19\n" +
+	"\bowner_id\x18\x12 \x01(\tR\aownerId\x12\x1d\n" +
+	"\n" +
+	"owner_name\x18\x13 \x01(\tR\townerName\x12\x1a\n" +
+	"\bseverity\x18\x14 \x01(\tR\bseverity\x12\x15\n" +
+	"\x06run_id\x18\x15 \x01(\tR\x05runId\x127\n" +
+	"\trun_start\x18\x16 \x01(\v2\x1a.google.protobuf.TimestampR\brunStart\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a<\n" +
+	"\x0eDocumentsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x12\n" +
+	"\x10_lifecycle_rules2N\n" +
+	"\x06Finder\x12D\n" +
+	"\n" +
+	"CheckNames\x12\x1b.s3finder.CheckNamesRequest\x1a\x15.s3finder.CheckResult(\x010\x01B'Z%github.com/magisterquis/s3finder;mainb\x06proto3"
+
+var (
+	file_s3finder_proto_rawDescOnce sync.Once
+	file_s3finder_proto_rawDescData []byte
+)
+
+func file_s3finder_proto_rawDescGZIP() []byte {
+	file_s3finder_proto_rawDescOnce.Do(func() {
+		file_s3finder_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_s3finder_proto_rawDesc), len(file_s3finder_proto_rawDesc)))
+	})
+	return file_s3finder_proto_rawDescData
+}
+
+var file_s3finder_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_s3finder_proto_goTypes = []any{
+	(*CheckNamesRequest)(nil),     // 0: s3finder.CheckNamesRequest
+	(*CheckResult)(nil),           // 1: s3finder.CheckResult
+	nil,                           // 2: s3finder.CheckResult.HeadersEntry
+	nil,                           // 3: s3finder.CheckResult.DocumentsEntry
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_s3finder_proto_depIdxs = []int32{
+	4, // 0: s3finder.CheckResult.timestamp:type_name -> google.protobuf.Timestamp
+	2, // 1: s3finder.CheckResult.headers:type_name -> s3finder.CheckResult.HeadersEntry
+	3, // 2: s3finder.CheckResult.documents:type_name -> s3finder.CheckResult.DocumentsEntry
+	4, // 3: s3finder.CheckResult.run_start:type_name -> google.protobuf.Timestamp
+	0, // 4: s3finder.Finder.CheckNames:input_type -> s3finder.CheckNamesRequest
+	1, // 5: s3finder.Finder.CheckNames:output_type -> s3finder.CheckResult
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_s3finder_proto_init() }
+func file_s3finder_proto_init() {
+	if File_s3finder_proto != nil {
+		return
+	}
+	file_s3finder_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_s3finder_proto_rawDesc), len(file_s3finder_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_s3finder_proto_goTypes,
+		DependencyIndexes: file_s3finder_proto_depIdxs,
+		MessageInfos:      file_s3finder_proto_msgTypes,
+	}.Build()
+	File_s3finder_proto = out.File
+	file_s3finder_proto_goTypes = nil
+	file_s3finder_proto_depIdxs = nil
+}
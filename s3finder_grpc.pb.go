@@ -0,0 +1,131 @@
+// s3finder.proto
+// gRPC interface for checking names
+// By J. Stuart McMurray
+// Created 20261014
+// Last Modified 20261014
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: s3finder.proto
+
+package main
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Finder_CheckNames_FullMethodName = "/s3finder.Finder/CheckNames"
+)
+
+// FinderClient is the client API for Finder service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Finder checks bucket names.
+type FinderClient interface {
+	// CheckNames checks the names streamed to it as-is, as with -raw, and
+	// streams back what's found.  Names S3 wouldn't allow are skipped.  A
+	// single name may have no results or more than one.
+	CheckNames(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckNamesRequest, CheckResult], error)
+}
+
+type finderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFinderClient(cc grpc.ClientConnInterface) FinderClient {
+	return &finderClient{cc}
+}
+
+func (c *finderClient) CheckNames(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CheckNamesRequest, CheckResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Finder_ServiceDesc.Streams[0], Finder_CheckNames_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CheckNamesRequest, CheckResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Finder_CheckNamesClient = grpc.BidiStreamingClient[CheckNamesRequest, CheckResult]
+
+// FinderServer is the server API for Finder service.
+// All implementations must embed UnimplementedFinderServer
+// for forward compatibility.
+//
+// Finder checks bucket names.
+type FinderServer interface {
+	// CheckNames checks the names streamed to it as-is, as with -raw, and
+	// streams back what's found.  Names S3 wouldn't allow are skipped.  A
+	// single name may have no results or more than one.
+	CheckNames(grpc.BidiStreamingServer[CheckNamesRequest, CheckResult]) error
+	mustEmbedUnimplementedFinderServer()
+}
+
+// UnimplementedFinderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFinderServer struct{}
+
+func (UnimplementedFinderServer) CheckNames(grpc.BidiStreamingServer[CheckNamesRequest, CheckResult]) error {
+	return status.Error(codes.Unimplemented, "method CheckNames not implemented")
+}
+func (UnimplementedFinderServer) mustEmbedUnimplementedFinderServer() {}
+func (UnimplementedFinderServer) testEmbeddedByValue()                {}
+
+// UnsafeFinderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FinderServer will
+// result in compilation errors.
+type UnsafeFinderServer interface {
+	mustEmbedUnimplementedFinderServer()
+}
+
+func RegisterFinderServer(s grpc.ServiceRegistrar, srv FinderServer) {
+	// If the following call panics, it indicates UnimplementedFinderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Finder_ServiceDesc, srv)
+}
+
+func _Finder_CheckNames_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FinderServer).CheckNames(&grpc.GenericServerStream[CheckNamesRequest, CheckResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Finder_CheckNamesServer = grpc.BidiStreamingServer[CheckNamesRequest, CheckResult]
+
+// Finder_ServiceDesc is the grpc.ServiceDesc for Finder service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Finder_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "s3finder.Finder",
+	HandlerType: (*FinderServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CheckNames",
+			Handler:       _Finder_CheckNames_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "s3finder.proto",
+}
@@ -0,0 +1,590 @@
+package main
+
+/*
+ * s3finder_test.go
+ * Tests for checking names against S3
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+/* newTestCheckConfig returns a minimal checkConfig which uses c to make
+requests and discards findings. */
+func newTestCheckConfig(c *http.Client) *checkConfig {
+	return &checkConfig{
+		client:     c,
+		slog:       log.New(io.Discard, "", 0),
+		requests:   newRequestCounts(),
+		unexpected: newRequestCounts(),
+		totals:     &runTotals{},
+	}
+}
+
+/* fakeS3 is a TLS server which gets every request made with its client,
+whichever host the request's URL names, so requests to S3's real endpoints
+can be answered by a handler. */
+type fakeS3 struct {
+	srv *httptest.Server
+
+	l    sync.Mutex
+	reqs []string /* Method, URL host, and Host header of each request */
+	snis []string /* SNI of each TLS handshake */
+}
+
+/* newFakeS3 starts a fakeS3 which answers requests with h.  It's closed when
+the test finishes. */
+func newFakeS3(t *testing.T, h http.HandlerFunc) *fakeS3 {
+	f := &fakeS3{srv: httptest.NewUnstartedServer(h)}
+	f.srv.TLS = &tls.Config{GetConfigForClient: func(
+		hi *tls.ClientHelloInfo,
+	) (*tls.Config, error) {
+		f.l.Lock()
+		defer f.l.Unlock()
+		f.snis = append(f.snis, hi.ServerName)
+		return nil, nil
+	}}
+	f.srv.StartTLS()
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+/* Client returns a client which sends every request to f without following
+redirects and notes the request. */
+func (f *fakeS3) Client() *http.Client {
+	addr := f.srv.Listener.Addr().String()
+	return &http.Client{
+		Transport: roundTripFunc(func(
+			req *http.Request,
+		) (*http.Response, error) {
+			f.l.Lock()
+			f.reqs = append(
+				f.reqs,
+				req.Method+" "+req.URL.Host+" "+req.Host,
+			)
+			f.l.Unlock()
+			return f.transport(addr).RoundTrip(req)
+		}),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+/* transport returns a transport which connects to addr for every request,
+with a new connection each time. */
+func (f *fakeS3) transport(addr string) *http.Transport {
+	return &http.Transport{
+		DialContext: func(
+			ctx context.Context,
+			network string,
+			_ string,
+		) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	}
+}
+
+/* Requests returns the method, URL host, and Host header of each request
+made with f's client, space-separated.  The Host header is the URL's host
+unless it's been changed. */
+func (f *fakeS3) Requests() []string {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return append([]string(nil), f.reqs...)
+}
+
+/* SNIs returns the SNI sent for each request made with f's client. */
+func (f *fakeS3) SNIs() []string {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return append([]string(nil), f.snis...)
+}
+
+/* roundTripFunc is an http.RoundTripper which calls itself. */
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+/* RoundTrip calls f. */
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+/* checkStandard checks the name n against the standard S3 endpoints, using
+client, and returns the results sent to conf's sinks. */
+func checkStandard(
+	t *testing.T,
+	n string,
+	client *http.Client,
+	conf *checkConfig,
+) []Result {
+	ep, err := newEndpoints(REGIONURL)
+	if nil != err {
+		t.Fatalf("newEndpoints: %v", err)
+	}
+	if nil == conf {
+		conf = newTestCheckConfig(client)
+	}
+	var sink sliceSink
+	conf.sinks = append(conf.sinks, &sink)
+	check(
+		context.Background(),
+		candidate{name: n, input: n},
+		"",
+		ep,
+		MAXRECURSION,
+		0,
+		conf,
+	)
+	return sink
+}
+
+/* equalStrings returns true if a and b hold the same strings. */
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/* TestCheckVirtualHostedOnly checks that a bucket which only works
+virtual-hosted-style is found after a bad request. */
+func TestCheckVirtualHostedOnly(t *testing.T) {
+	f := newFakeS3(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "bucket.s3.amazonaws.com":
+			io.WriteString(
+				w,
+				"<ListBucketResult></ListBucketResult>",
+			)
+		case "bucket", "dotted.bucket":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	/* Undotted names are retried virtual-hosted-style */
+	rs := checkStandard(t, "bucket", f.Client(), nil)
+	want := []string{
+		"GET s3.amazonaws.com bucket",
+		"GET bucket.s3.amazonaws.com bucket.s3.amazonaws.com",
+	}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Fatalf("Requests:\ngot  %q\nwant %q", got, want)
+	}
+	if 1 != len(rs) {
+		t.Fatalf("Got %v results, want 1", len(rs))
+	}
+	if StatusPublic != rs[0].Status ||
+		"https://bucket.s3.amazonaws.com" != rs[0].BucketURL {
+		t.Errorf(
+			"Got %v bucket at %v, want %v bucket at %v",
+			rs[0].Status,
+			rs[0].BucketURL,
+			StatusPublic,
+			"https://bucket.s3.amazonaws.com",
+		)
+	}
+
+	/* Dotted names would fail TLS verification */
+	f = newFakeS3(t, f.srv.Config.Handler.ServeHTTP)
+	rs = checkStandard(t, "dotted.bucket", f.Client(), nil)
+	want = []string{"GET s3.amazonaws.com dotted.bucket"}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Errorf("Dotted requests:\ngot  %q\nwant %q", got, want)
+	}
+	if 0 != len(rs) {
+		t.Errorf("Got %v results for dotted name, want 0", len(rs))
+	}
+}
+
+/* TestCertNamesHyphenated checks that names from certificates produce the
+fully-hyphenated bucket names which are often real buckets. */
+func TestCertNamesHyphenated(t *testing.T) {
+	for _, c := range []struct {
+		certName string
+		tags     []string
+		want     []string
+	}{{
+		certName: "assets.example.com",
+		want:     []string{"assets-example-com", "example-com"},
+	}, {
+		certName: "*.assets.example.com",
+		want:     []string{"assets-example-com", "example-com"},
+	}, {
+		certName: "cdn.assets.example.co.uk",
+		want: []string{
+			"cdn-assets-example-co-uk",
+			"assets-example-co-uk",
+			"example-co-uk",
+		},
+	}, {
+		certName: "assets.example.com",
+		tags:     []string{"dev"},
+		want: []string{
+			"assets-example-com",
+			"assets-example-com-dev",
+			"dev-assets-example-com",
+		},
+	}, {
+		certName: "a*.example.com",
+	}} {
+		var (
+			namech   = make(chan string)
+			bucketch = make(chan candidate)
+			got      = make(map[string]struct{})
+		)
+		go func() {
+			defer close(namech)
+			sendCertNames(namech, []string{c.certName})
+		}()
+		go processNames(
+			bucketch,
+			namech,
+			newTestNameConfig(t, c.tags, nil),
+			false,
+		)
+		for cand := range bucketch {
+			got[cand.name] = struct{}{}
+		}
+		if nil == c.want && 0 != len(got) {
+			t.Errorf("%q: got unwanted names %v", c.certName, got)
+		}
+		for _, w := range c.want {
+			if _, ok := got[w]; !ok {
+				t.Errorf("%q: %q not generated", c.certName, w)
+			}
+		}
+	}
+}
+
+/* timeoutError is a net.Error which is a timeout. */
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+/* requestErrors are errors a request might return and how check should treat
+them. */
+var requestErrors = []struct {
+	name  string
+	err   error
+	retry string /* retryReason, or the empty string for no retry */
+}{{
+	name: "dns",
+	err: &net.DNSError{
+		Err:        "no such host",
+		Name:       "s3.eu-west-1.amazonaws.com",
+		IsNotFound: true,
+	},
+}, {
+	name: "reset",
+	err: &net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: os.NewSyscallError("read", syscall.ECONNRESET),
+	},
+	retry: "connection reset",
+}, {
+	name:  "reset_string",
+	err:   errors.New("read tcp: connection reset by peer"),
+	retry: "connection reset",
+}, {
+	name:  "eof",
+	err:   io.EOF,
+	retry: "EOF",
+}, {
+	name:  "unexpected_eof",
+	err:   fmt.Errorf("reading: %w", io.ErrUnexpectedEOF),
+	retry: "EOF",
+}, {
+	name: "no_route",
+	err: &net.OpError{
+		Op:  "dial",
+		Net: "tcp",
+		Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH),
+	},
+	retry: "route error",
+}, {
+	name:  "tls_timeout",
+	err:   errors.New("net/http: TLS handshake timeout"),
+	retry: "TLS handshake timeout",
+}, {
+	name:  "timeout",
+	err:   &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}},
+	retry: "timeout",
+}, {
+	name: "other",
+	err:  errors.New("kittens"),
+}}
+
+func TestRetryReason(t *testing.T) {
+	for _, c := range requestErrors {
+		if got := retryReason(c.err); c.retry != got {
+			t.Errorf("%v: got %q, want %q", c.name, got, c.retry)
+		}
+	}
+}
+
+/* TestCheckRequestErrors injects each error into a check's first request
+and makes sure only the errors worth retrying are retried. */
+func TestCheckRequestErrors(t *testing.T) {
+	for _, c := range requestErrors {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			var n int32
+			client := &http.Client{Transport: roundTripFunc(func(
+				req *http.Request,
+			) (*http.Response, error) {
+				if 1 == atomic.AddInt32(&n, 1) {
+					return nil, c.err
+				}
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			})}
+			checkStandard(t, "bucket", client, nil)
+			want := int32(1)
+			if "" != c.retry {
+				want = 2
+			}
+			if got := atomic.LoadInt32(&n); want != got {
+				t.Errorf("Made %v requests, want %v", got, want)
+			}
+		})
+	}
+}
+
+/* TestCheckSNI checks that the TLS server name is the endpoint's, not a
+dotted bucket's, and that dotted names aren't checked where the bucket's
+name would be the TLS server name. */
+func TestCheckSNI(t *testing.T) {
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	/* Path-style */
+	f := newFakeS3(t, notFound)
+	checkStandard(t, "assets.example.com", f.Client(), nil)
+	if want, got := []string{
+		"GET s3.amazonaws.com assets.example.com",
+	}, f.Requests(); !equalStrings(want, got) {
+		t.Errorf("Path-style requests:\ngot  %q\nwant %q", got, want)
+	}
+	if want, got := []string{
+		"s3.amazonaws.com",
+	}, f.SNIs(); !equalStrings(want, got) {
+		t.Errorf("Path-style SNIs:\ngot  %q\nwant %q", got, want)
+	}
+
+	/* Virtual-hosted-style */
+	for _, c := range []struct {
+		name string
+		snis []string
+	}{
+		{"assets", []string{"assets.s3-accelerate.amazonaws.com"}},
+		{"assets.example.com", nil},
+	} {
+		f := newFakeS3(t, notFound)
+		s3Provider{ep: ACCELERATEENDPOINTS}.Check(
+			context.Background(),
+			candidate{name: c.name, input: c.name},
+			0,
+			newTestCheckConfig(f.Client()),
+		)
+		if got := f.SNIs(); !equalStrings(c.snis, got) {
+			t.Errorf(
+				"Virtual-hosted-style SNIs for %v:\n"+
+					"got  %q\nwant %q",
+				c.name,
+				got,
+				c.snis,
+			)
+		}
+	}
+}
+
+/* TestCheckHeadRegion checks that -head-region finds a bucket's region with
+a HEAD request and checks the bucket there with one more request, and that
+names which aren't buckets only take the HEAD request. */
+func TestCheckHeadRegion(t *testing.T) {
+	f := newFakeS3(t, func(w http.ResponseWriter, r *http.Request) {
+		if "bucket" != r.Host {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if http.MethodHead == r.Method {
+			w.Header().Set("x-amz-bucket-region", "eu-west-1")
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+		io.WriteString(w, "<ListBucketResult></ListBucketResult>")
+	})
+	conf := newTestCheckConfig(f.Client())
+	conf.headRegion = true
+	rs := checkStandard(t, "bucket", f.Client(), conf)
+	want := []string{
+		"HEAD s3.amazonaws.com bucket",
+		"GET s3.eu-west-1.amazonaws.com bucket",
+	}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Fatalf("Requests:\ngot  %q\nwant %q", got, want)
+	}
+	wantURL := "https://s3.eu-west-1.amazonaws.com/bucket"
+	if 1 != len(rs) ||
+		StatusPublic != rs[0].Status ||
+		wantURL != rs[0].BucketURL {
+		t.Errorf(
+			"Got results %+v, want a public bucket at %v",
+			rs,
+			wantURL,
+		)
+	}
+
+	/* Not a bucket */
+	f = newFakeS3(t, f.srv.Config.Handler.ServeHTTP)
+	conf = newTestCheckConfig(f.Client())
+	conf.headRegion = true
+	checkStandard(t, "nonbucket", f.Client(), conf)
+	want = []string{"HEAD s3.amazonaws.com nonbucket"}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Errorf("Non-bucket requests:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+/* TestCheckStartRegion checks that checks with a start region start at the
+region's partition's endpoint, without a HEAD request to the global endpoint,
+which doesn't know about the bucket. */
+func TestCheckStartRegion(t *testing.T) {
+	f := newFakeS3(t, func(w http.ResponseWriter, r *http.Request) {
+		if "s3.cn-north-1.amazonaws.com.cn" != r.TLS.ServerName {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "<ListBucketResult></ListBucketResult>")
+	})
+	ep, err := newEndpoints(REGIONURL)
+	if nil != err {
+		t.Fatalf("newEndpoints: %v", err)
+	}
+	ep.start = "cn-north-1"
+	conf := newTestCheckConfig(f.Client())
+	conf.headRegion = true
+	var sink sliceSink
+	conf.sinks = append(conf.sinks, &sink)
+	s3Provider{ep: ep}.Check(
+		context.Background(),
+		candidate{name: "bucket", input: "bucket"},
+		0,
+		conf,
+	)
+	want := []string{"GET s3.cn-north-1.amazonaws.com.cn bucket"}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Fatalf("Requests:\ngot  %q\nwant %q", got, want)
+	}
+	wantURL := "https://s3.cn-north-1.amazonaws.com.cn/bucket"
+	if 1 != len(sink) ||
+		StatusPublic != sink[0].Status ||
+		wantURL != sink[0].BucketURL {
+		t.Errorf(
+			"Got results %+v, want a public bucket at %v",
+			sink,
+			wantURL,
+		)
+	}
+}
+
+/* TestCheckTemporaryRedirect checks that a 302 to a regional endpoint, such
+as S3 sends for new buckets, is followed to the bucket's region. */
+func TestCheckTemporaryRedirect(t *testing.T) {
+	f := newFakeS3(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.TLS.ServerName {
+		case "s3.amazonaws.com":
+			w.Header().Set(
+				"Location",
+				"https://bucket.s3.eu-west-1.amazonaws.com/",
+			)
+			w.WriteHeader(http.StatusFound)
+		case "s3.eu-west-1.amazonaws.com":
+			io.WriteString(
+				w,
+				"<ListBucketResult></ListBucketResult>",
+			)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	rs := checkStandard(t, "bucket", f.Client(), nil)
+	want := []string{
+		"GET s3.amazonaws.com bucket",
+		"GET s3.eu-west-1.amazonaws.com bucket",
+	}
+	if got := f.Requests(); !equalStrings(want, got) {
+		t.Fatalf("Requests:\ngot  %q\nwant %q", got, want)
+	}
+	wantURL := "https://s3.eu-west-1.amazonaws.com/bucket"
+	if 1 != len(rs) ||
+		StatusPublic != rs[0].Status ||
+		wantURL != rs[0].BucketURL {
+		t.Errorf(
+			"Got results %+v, want a public bucket at %v",
+			rs,
+			wantURL,
+		)
+	}
+}
+
+/* TestCheckSuspended checks that buckets nobody may access are reported as
+suspended, with a low severity. */
+func TestCheckSuspended(t *testing.T) {
+	f := newFakeS3(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(
+			w,
+			"<Error><Code>AllAccessDisabled</Code>"+
+				"<Message>All access to this object has "+
+				"been disabled</Message></Error>",
+		)
+	})
+	rs := checkStandard(t, "bucket", f.Client(), nil)
+	if 1 != len(rs) {
+		t.Fatalf("Got %v results, want 1", len(rs))
+	}
+	if StatusSuspended != rs[0].Status || SeverityLow != rs[0].Severity {
+		t.Errorf(
+			"Got %v result with severity %v, want %v with %v",
+			rs[0].Status,
+			rs[0].Severity,
+			StatusSuspended,
+			SeverityLow,
+		)
+	}
+}
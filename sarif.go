@@ -0,0 +1,217 @@
+package main
+
+/*
+ * sarif.go
+ * Write findings as SARIF
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SARIFSCHEMA is the schema for the SARIF we write
+	SARIFSCHEMA = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SARIFVERSION is the version of SARIF we write
+	SARIFVERSION = "2.1.0"
+
+	// SARIFRULE is the ID of the single rule findings are reported under
+	SARIFRULE = "public-s3-bucket"
+
+	// SARIFINFOURI is where to find out more about the tool
+	SARIFINFOURI = "https://github.com/magisterquis/s3finder"
+)
+
+/* sarifLevels maps statuses to SARIF levels.  Statuses not in sarifLevels
+are notes. */
+var sarifLevels = map[string]string{
+	StatusPublic:        "error",
+	StatusPublicPrefix:  "error",
+	StatusRequesterPays: "warning",
+	StatusRedirect:      "warning",
+	StatusSuspended:     "warning",
+}
+
+/* sarifLog is the top-level SARIF document. */
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+/* sarifRun is a single run of s3finder. */
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			Version        string      `json:"version"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	AutomationDetails *sarifAutomation  `json:"automationDetails,omitempty"`
+	Invocations       []sarifInvocation `json:"invocations"`
+	Results           []sarifResult     `json:"results"`
+}
+
+/* sarifAutomation identifies a run. */
+type sarifAutomation struct {
+	ID string `json:"id"`
+}
+
+/* sarifRule describes what a finding means. */
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri"`
+}
+
+/* sarifInvocation describes how s3finder was run. */
+type sarifInvocation struct {
+	CommandLine         string   `json:"commandLine"`
+	Arguments           []string `json:"arguments"`
+	ExecutionSuccessful bool     `json:"executionSuccessful"`
+	StartTimeUTC        string   `json:"startTimeUtc"`
+	EndTimeUTC          string   `json:"endTimeUtc"`
+}
+
+/* sarifResult is a single finding. */
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Props     struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Severity   string `json:"severity,omitempty"`
+		HTTPStatus int    `json:"httpStatus,omitempty"`
+		Region     string `json:"region,omitempty"`
+	} `json:"properties"`
+}
+
+/* sarifMessage is a bit of text. */
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+/* sarifLocation is where a finding was found. */
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+/* toolVersion returns s3finder's version, as best we can tell. */
+func toolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || "" == bi.Main.Version {
+		return "(devel)"
+	}
+	return bi.Main.Version
+}
+
+/* writeSARIF writes the findings in rs to the file named fn as a SARIF log
+for a run which started at start and ends now.  If runID isn't the empty
+string, it's used to identify the run. */
+func writeSARIF(fn string, rs []Result, start time.Time, runID string) error {
+	var run sarifRun
+	if "" != runID {
+		run.AutomationDetails = &sarifAutomation{ID: runID}
+	}
+	run.Tool.Driver.Name = "s3finder"
+	run.Tool.Driver.Version = toolVersion()
+	run.Tool.Driver.InformationURI = SARIFINFOURI
+	run.Tool.Driver.Rules = []sarifRule{{
+		ID: SARIFRULE,
+		ShortDescription: sarifMessage{
+			Text: "S3 bucket exists and may be publicly accessible",
+		},
+		HelpURI: SARIFINFOURI,
+	}}
+	/* Don't put secrets in dashboards. */
+	args := redactedArgs(flag.CommandLine, os.Args[1:])
+	cl := commandLine(append([]string{os.Args[0]}, args...))
+	run.Invocations = []sarifInvocation{{
+		CommandLine:         cl,
+		Arguments:           args,
+		ExecutionSuccessful: true,
+		StartTimeUTC:        start.UTC().Format(time.RFC3339),
+		EndTimeUTC:          time.Now().UTC().Format(time.RFC3339),
+	}}
+
+	/* Add the findings */
+	run.Results = make([]sarifResult, 0, len(rs))
+	for _, r := range rs {
+		var sr sarifResult
+		sr.RuleID = SARIFRULE
+		sr.Level = sarifLevels[r.Status]
+		if "" == sr.Level {
+			sr.Level = "note"
+		}
+		sr.Message.Text = fmt.Sprintf(
+			"Bucket %v is %v",
+			r.Name,
+			r.Status,
+		)
+		if "" != r.Region {
+			sr.Message.Text += " in " + r.Region
+		}
+		var l sarifLocation
+		l.PhysicalLocation.ArtifactLocation.URI = r.BucketURL
+		sr.Locations = []sarifLocation{l}
+		sr.Props.Name = r.Name
+		sr.Props.Status = r.Status
+		sr.Props.Severity = r.Severity
+		sr.Props.HTTPStatus = r.HTTPStatus
+		sr.Props.Region = r.Region
+		run.Results = append(run.Results, sr)
+	}
+
+	/* Write it out */
+	f, err := os.Create(fn)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sarifLog{
+		Schema:  SARIFSCHEMA,
+		Version: SARIFVERSION,
+		Runs:    []sarifRun{run},
+	}); nil != err {
+		return err
+	}
+	return f.Close()
+}
+
+// SHELLSPECIAL are the characters which cause commandLine to quote an
+// argument
+const SHELLSPECIAL = " \t\n\"'\\$`|&;<>()*?[]{}"
+
+/* commandLine returns args as a single string, quoting arguments which need
+it. */
+func commandLine(args []string) string {
+	qs := make([]string, len(args))
+	for i, a := range args {
+		if "" == a || strings.ContainsAny(a, SHELLSPECIAL) {
+			a = strconv.Quote(a)
+		}
+		qs[i] = a
+	}
+	return strings.Join(qs, " ")
+}
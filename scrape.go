@@ -0,0 +1,137 @@
+package main
+
+/*
+ * scrape.go
+ * Find bucket names in robots.txt and sitemaps
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SCRAPETIMEOUT is how long to wait for a single scraped file
+	SCRAPETIMEOUT = 30 * time.Second
+
+	// SCRAPEMAXBYTES is the maximum number of bytes to read from a single
+	// scraped file
+	SCRAPEMAXBYTES = 10 * 1024 * 1024
+)
+
+/* SCRAPEPATHS are the paths on a site which are scraped for names. */
+var SCRAPEPATHS = []string{"/robots.txt", "/sitemap.xml"}
+
+var (
+	/* virtualHostRE finds buckets in virtual-hosted-style S3 URLs, like
+	bucket.s3.us-east-1.amazonaws.com */
+	virtualHostRE = regexp.MustCompile(
+		`(?i)([a-z0-9][a-z0-9.-]*)` +
+			`\.s3(?:[.-][a-z0-9-]+)*\.amazonaws\.com`,
+	)
+
+	/* pathStyleRE finds buckets in path-style S3 URLs, like
+	s3.us-east-1.amazonaws.com/bucket */
+	pathStyleRE = regexp.MustCompile(
+		`(?i)(?:^|[^a-z0-9.-])s3(?:[.-][a-z0-9-]+)*\.amazonaws\.com/` +
+			`([a-z0-9][a-z0-9.-]*[a-z0-9])`,
+	)
+
+	/* urlHostRE finds the hosts in URLs */
+	urlHostRE = regexp.MustCompile(`(?i)https?://([a-z0-9.-]+)`)
+)
+
+/* getScrapedNames sends to out anything on ns, plus, for names with a dot,
+any buckets and subdomains referenced in the site's robots.txt and
+sitemap.xml, fetched with c.  Which names were found by scraping is noted in
+found. */
+func getScrapedNames(
+	out chan<- string,
+	ns <-chan string,
+	c *http.Client,
+	found *nameSources,
+) {
+	defer close(out)
+	for n := range ns {
+		/* Send out original name */
+		out <- n
+		/* Skip non-domains */
+		if !strings.Contains(n, ".") {
+			continue
+		}
+		/* Send out what the site tells us about */
+		for _, s := range scrapeSite(n, c) {
+			found.Set(s, SourceScrape)
+			out <- s
+		}
+	}
+}
+
+/* scrapeSite returns the buckets and subdomains of the domain d referenced in
+the files in SCRAPEPATHS on https://d, fetched with c, sorted.  Errors are
+logged. */
+func scrapeSite(d string, c *http.Client) []string {
+	m := make(map[string]struct{})
+	for _, p := range SCRAPEPATHS {
+		u := "https://" + d + p
+		b, err := scrapeFile(u, c)
+		if nil != err {
+			elog.Printf("[%v] Unable to scrape %v: %v", d, u, err)
+			continue
+		}
+		for _, n := range scrapedNames(d, string(b)) {
+			m[n] = struct{}{}
+		}
+	}
+	ns := make([]string, 0, len(m))
+	for n := range m {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+/* scrapeFile gets up to SCRAPEMAXBYTES bytes of the file at u with c.  A
+missing file isn't an error, but has no contents. */
+func scrapeFile(u string, c *http.Client) ([]byte, error) {
+	res, err := c.Get(u)
+	if nil != err {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected response %v", res.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(res.Body, SCRAPEMAXBYTES))
+}
+
+/* scrapedNames returns the bucket names in S3 URLs and hostnames of
+subdomains of d in s, lowercased. */
+func scrapedNames(d, s string) []string {
+	var ns []string
+	for _, re := range []*regexp.Regexp{virtualHostRE, pathStyleRE} {
+		for _, m := range re.FindAllStringSubmatch(s, -1) {
+			ns = append(ns, strings.ToLower(m[1]))
+		}
+	}
+	for _, m := range urlHostRE.FindAllStringSubmatch(s, -1) {
+		h := strings.TrimSuffix(strings.ToLower(m[1]), ".")
+		if strings.HasSuffix(h, "."+d) {
+			ns = append(ns, h)
+		}
+	}
+	return ns
+}
@@ -0,0 +1,75 @@
+package main
+
+/*
+ * seen.go
+ * Remember which names we've already processed
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+/* nameSet remembers which names have been processed. */
+type nameSet interface {
+	/* Seen returns true if n has been added */
+	Seen(n string) bool
+
+	/* Add notes that n has been seen */
+	Add(n string)
+}
+
+/* seenNames is a nameSet which normally keeps names in an LRU cache, so very
+old names may be forgotten, but if the cache can't be made they're kept in a
+map, which never forgets.  It is safe to call seenNames' methods from multiple
+goroutines. */
+type seenNames struct {
+	c *lru.Cache
+
+	/* l protects m, which is only used without c */
+	l sync.Mutex
+	m map[string]struct{}
+}
+
+/* newSeenNames returns a seenNames which remembers up to size names.  If the
+LRU cache can't be made, a warning is logged and the names are kept in a map
+instead. */
+func newSeenNames(size int) *seenNames {
+	c, err := lru.New(size)
+	if nil != err {
+		elog.Printf(
+			"Unable to make seen name cache, using an unbounded "+
+				"map instead: %v",
+			err,
+		)
+		return &seenNames{m: make(map[string]struct{})}
+	}
+	return &seenNames{c: c}
+}
+
+/* Seen returns true if n has been added. */
+func (s *seenNames) Seen(n string) bool {
+	if nil != s.c {
+		_, ok := s.c.Get(n)
+		return ok
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	_, ok := s.m[n]
+	return ok
+}
+
+/* Add notes that n has been seen. */
+func (s *seenNames) Add(n string) {
+	if nil != s.c {
+		s.c.Add(n, nil)
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.m[n] = struct{}{}
+}
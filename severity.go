@@ -0,0 +1,110 @@
+package main
+
+/*
+ * severity.go
+ * How bad is a finding
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Finding severities, least severe first
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+/* severityRanks orders the severities.  Results without a severity have a
+rank of 0. */
+var severityRanks = map[string]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+/* parseSeverity checks that s is a severity and returns it.  The empty string
+is allowed, and means any result, even without a severity. */
+func parseSeverity(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := severityRanks[s]; !ok && "" != s {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return s, nil
+}
+
+/* severityAtLeast returns true if the severity s is at least min. */
+func severityAtLeast(s, min string) bool {
+	return severityRanks[s] >= severityRanks[min]
+}
+
+/* severityOf works out r's severity from its status and whatever else we know
+about its bucket.  Results for names which aren't buckets have no severity. */
+func severityOf(r Result) string {
+	if StatusNotBucket == r.Status {
+		return ""
+	}
+	/* Anybody can change what's there */
+	if aclWorldWritable(r.Documents["acl"]) {
+		return SeverityCritical
+	}
+	switch r.Status {
+	case StatusPublic, StatusPublicPrefix:
+		/* Old versions of things are probably there, too */
+		if VersioningEnabled == r.Versioning {
+			return SeverityHigh
+		}
+		return SeverityMedium
+	case StatusRequesterPays, StatusRedirect:
+		return SeverityLow
+	case StatusSuspended:
+		/* Nothing to read, but the name may be free for the taking
+		once the account's closed */
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+/* aclWorldWritable returns true if the ACL document doc lets anybody, or any
+AWS user, write to the bucket. */
+func aclWorldWritable(doc string) bool {
+	if "" == doc {
+		return false
+	}
+	var acl struct {
+		Grants []struct {
+			Grantee struct {
+				URI string
+			}
+			Permission string
+		} `xml:"AccessControlList>Grant"`
+	}
+	if err := xml.Unmarshal([]byte(doc), &acl); nil != err {
+		return false
+	}
+	for _, g := range acl.Grants {
+		if !strings.HasSuffix(g.Grantee.URI, "/global/AllUsers") &&
+			!strings.HasSuffix(
+				g.Grantee.URI,
+				"/global/AuthenticatedUsers",
+			) {
+			continue
+		}
+		switch g.Permission {
+		case "WRITE", "WRITE_ACP", "FULL_CONTROL":
+			return true
+		}
+	}
+	return false
+}
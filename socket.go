@@ -0,0 +1,125 @@
+package main
+
+/*
+ * socket.go
+ * Stream results over a Unix socket
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"sync"
+)
+
+// SOCKETBUFLEN is the number of results to buffer for each socket client
+// before results are dropped
+const SOCKETBUFLEN = 1024
+
+/* socketServer sends every Result it's sent as a JSON line to every client
+connected to a Unix socket.  Slow clients miss results rather than holding up
+checks. */
+type socketServer struct {
+	l       sync.Mutex
+	ln      net.Listener
+	clients map[chan []byte]struct{}
+}
+
+/* newSocketServer listens on the Unix socket at path, removing any stale
+socket first.  If path is the empty string, newSocketServer returns nil. */
+func newSocketServer(path string) (*socketServer, error) {
+	if "" == path {
+		return nil, nil
+	}
+
+	/* Remove a socket left by a previous run, but nothing else */
+	if fi, err := os.Lstat(path); nil == err &&
+		0 != fi.Mode()&os.ModeSocket {
+		if err := os.Remove(path); nil != err {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if nil != err {
+		return nil, err
+	}
+	s := &socketServer{
+		ln:      ln,
+		clients: make(map[chan []byte]struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+/* accept accepts clients until the listener is closed. */
+func (s *socketServer) accept() {
+	for {
+		c, err := s.ln.Accept()
+		if nil != err {
+			return
+		}
+		ch := make(chan []byte, SOCKETBUFLEN)
+		s.l.Lock()
+		s.clients[ch] = struct{}{}
+		s.l.Unlock()
+		go s.handle(c, ch)
+	}
+}
+
+/* handle writes the lines sent on ch to c until ch is closed or a write
+fails. */
+func (s *socketServer) handle(c net.Conn, ch chan []byte) {
+	defer c.Close()
+	for b := range ch {
+		if _, err := c.Write(b); nil != err {
+			/* Client's gone, stop sending it things */
+			s.l.Lock()
+			if _, ok := s.clients[ch]; ok {
+				delete(s.clients, ch)
+				close(ch)
+			}
+			s.l.Unlock()
+			return
+		}
+	}
+}
+
+/* Send sends r to every connected client. */
+func (s *socketServer) Send(r Result) {
+	b, err := json.Marshal(r)
+	if nil != err {
+		elog.Printf("Unable to encode result for %v: %v", r.Name, err)
+		return
+	}
+	b = append(b, '\n')
+	s.l.Lock()
+	defer s.l.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- b:
+		default:
+			log.Printf(
+				"[%v] Socket client too slow, dropping result",
+				r.Name,
+			)
+		}
+	}
+}
+
+/* Close stops listening and disconnects clients once they've been sent the
+results already queued for them. */
+func (s *socketServer) Close() error {
+	err := s.ln.Close()
+	s.l.Lock()
+	defer s.l.Unlock()
+	for ch := range s.clients {
+		delete(s.clients, ch)
+		close(ch)
+	}
+	return err
+}
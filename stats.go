@@ -0,0 +1,160 @@
+package main
+
+/*
+ * stats.go
+ * Count requests and what a run's done
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/* requestCounts counts requests by what they were sent to or what came back.
+It is safe to call requestCounts' methods from multiple goroutines. */
+type requestCounts struct {
+	l sync.Mutex
+	m map[string]uint
+}
+
+/* newRequestCounts returns a new requestCounts. */
+func newRequestCounts() *requestCounts {
+	return &requestCounts{m: make(map[string]uint)}
+}
+
+/* Add counts a request sent to, or answered with, what. */
+func (c *requestCounts) Add(what string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.m[what]++
+}
+
+/* Counts returns a copy of the number of requests, by what they were sent to
+or answered with. */
+func (c *requestCounts) Counts() map[string]uint {
+	c.l.Lock()
+	defer c.l.Unlock()
+	m := make(map[string]uint, len(c.m))
+	for k, v := range c.m {
+		m[k] = v
+	}
+	return m
+}
+
+/* String returns the total number of requests with a breakdown by what they
+were sent to or answered with. */
+func (c *requestCounts) String() string {
+	c.l.Lock()
+	defer c.l.Unlock()
+	var (
+		total uint
+		parts = make([]string, 0, len(c.m))
+	)
+	for w, n := range c.m {
+		total += n
+		parts = append(parts, fmt.Sprintf("%v: %v", w, n))
+	}
+	if 0 == total {
+		return "none"
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("%v (%v)", total, strings.Join(parts, ", "))
+}
+
+/* failPolicy is a resultSink which counts results with statuses or
+severities which should cause a nonzero exit. */
+type failPolicy struct {
+	l           sync.Mutex
+	statuses    map[string]struct{}
+	minSeverity string /* Empty for no severity */
+	n           uint
+}
+
+/* newFailPolicy returns a failPolicy which counts results with the statuses
+in the comma-separated list s, or with at least the lowest of the severities
+in s, or nil if s is "none" or the empty string. */
+func newFailPolicy(s string) (*failPolicy, error) {
+	p := &failPolicy{statuses: make(map[string]struct{})}
+	for _, st := range strings.Split(s, ",") {
+		switch st = strings.TrimSpace(st); st {
+		case "", "none":
+		case StatusPublic,
+			StatusRequesterPays,
+			StatusForbidden,
+			StatusPublicPrefix,
+			StatusSuspended:
+			p.statuses[st] = struct{}{}
+		case SeverityInfo,
+			SeverityLow,
+			SeverityMedium,
+			SeverityHigh,
+			SeverityCritical:
+			if "" == p.minSeverity ||
+				!severityAtLeast(st, p.minSeverity) {
+				p.minSeverity = st
+			}
+		default:
+			return nil, fmt.Errorf("unknown status %q", st)
+		}
+	}
+	if 0 == len(p.statuses) && "" == p.minSeverity {
+		return nil, nil
+	}
+	return p, nil
+}
+
+/* Send counts r if it has one of p's statuses or a high enough severity. */
+func (p *failPolicy) Send(r Result) {
+	_, ok := p.statuses[r.Status]
+	if !ok && ("" == p.minSeverity ||
+		!severityAtLeast(r.Severity, p.minSeverity)) {
+		return
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.n++
+}
+
+/* Failed returns the number of results counted, or 0 if p is nil. */
+func (p *failPolicy) Failed() uint {
+	if nil == p {
+		return 0
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.n
+}
+
+/* runTotals counts what a run's done, for a summary at the end.  It is safe
+to call runTotals' methods from multiple goroutines. */
+type runTotals struct {
+	names      uint64
+	candidates uint64
+	public     uint64
+}
+
+/* Name counts an input name processed. */
+func (t *runTotals) Name() { atomic.AddUint64(&t.names, 1) }
+
+/* Candidate counts a candidate bucket name generated. */
+func (t *runTotals) Candidate() { atomic.AddUint64(&t.candidates, 1) }
+
+/* Public counts a public bucket found. */
+func (t *runTotals) Public() { atomic.AddUint64(&t.public, 1) }
+
+/* String summarizes the totals. */
+func (t *runTotals) String() string {
+	return fmt.Sprintf(
+		"names processed: %v, candidates generated: %v, public "+
+			"buckets found: %v",
+		atomic.LoadUint64(&t.names),
+		atomic.LoadUint64(&t.candidates),
+		atomic.LoadUint64(&t.public),
+	)
+}
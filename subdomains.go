@@ -0,0 +1,343 @@
+package main
+
+/*
+ * subdomains.go
+ * Find more subdomains
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+/* subdomainSource finds subdomains of a domain. */
+type subdomainSource interface {
+	/* Subdomains returns subdomains of d.  It returns an empty slice and
+	no error if none were found. */
+	Subdomains(d string) ([]string, error)
+
+	/* String describes the source, for logging */
+	String() string
+
+	/* Source returns the candidate source for names found by the
+	subdomainSource */
+	Source() string
+}
+
+// SUBDOMAINQUEUE is the number of domains which may be waiting for each
+// subdomain source
+const SUBDOMAINQUEUE = 10240
+
+/* getSubdomainNames sends to out anything on ns, plus any names of subdomains
+of names on ns found by srcs if the name contains a dot.  Names on ns are sent
+as soon as they're received, and each source is queried in its own goroutine,
+so a slow query for one domain doesn't hold up checks for the next.  Subdomains
+found are written to subs and which source found them is noted in found.  Once
+ctx is done, queued domains are skipped rather than queried. */
+func getSubdomainNames(
+	ctx context.Context,
+	out chan<- string,
+	ns <-chan string,
+	srcs []subdomainSource,
+	subs *lineFile,
+	found *nameSources,
+) {
+	defer close(out)
+
+	/* Query each source in the background */
+	var (
+		wg sync.WaitGroup
+		qs = make([]chan string, len(srcs))
+	)
+	for i, src := range srcs {
+		qs[i] = make(chan string, SUBDOMAINQUEUE)
+		wg.Add(1)
+		go func(src subdomainSource, q <-chan string) {
+			defer wg.Done()
+			for n := range q {
+				if nil != ctx.Err() {
+					continue
+				}
+				querySubdomains(out, n, src, subs, found)
+			}
+		}(src, qs[i])
+	}
+
+	for n := range ns {
+		/* Send out original name */
+		out <- n
+		/* Skip non-domains */
+		if !strings.Contains(n, ".") {
+			continue
+		}
+		/* Queue it up for subdomains */
+		for _, q := range qs {
+			q <- n
+		}
+	}
+
+	/* Wait for the queries to finish */
+	for _, q := range qs {
+		close(q)
+	}
+	wg.Wait()
+}
+
+/* querySubdomains sends the subdomains of n found by src to out, for
+getSubdomainNames. */
+func querySubdomains(
+	out chan<- string,
+	n string,
+	src subdomainSource,
+	subs *lineFile,
+	found *nameSources,
+) {
+	span := startSubdomainSpan(src, n)
+	ss, err := src.Subdomains(n)
+	span.End()
+	if nil != err {
+		elog.Printf(
+			"Unable to query %v for subdomains of %v: %v",
+			src,
+			n,
+			err,
+		)
+		return
+	}
+	for _, s := range ss {
+		if err := subs.WriteLine(s); nil != err {
+			elog.Printf("Error writing subdomain %v: %v", s, err)
+		}
+		found.Set(s, src.Source())
+		out <- s
+	}
+}
+
+/* crtshSource is a subdomainSource which queries crt.sh with the query string
+template query, as returned by newCTLQuery.  At most maxBytes bytes of each
+response are read.  Unchanged results for queries in state aren't fetched
+again.  Queries are spaced out by limiter and held while pause is paused.
+The domain and its parents, down to its registrable domain as determined by
+suffixes, are queried, but only the first levels of them, most-specific first,
+or only the registrable domain if levels is 0.  Levels in queried aren't
+queried again. */
+type crtshSource struct {
+	query    string
+	maxBytes int64
+	state    *ctlState
+	limiter  *rateLimiter
+	pause    *pauser
+	levels   uint
+	suffixes *suffixList
+	queried  nameSet
+}
+
+/* Subdomains queries crt.sh for subdomains of d and its parents. */
+func (c crtshSource) Subdomains(d string) ([]string, error) {
+	var ns []string
+	for _, l := range c.ctlLevels(d) {
+		if c.queried.Seen(l) {
+			continue
+		}
+		c.pause.Wait()
+		c.limiter.Wait()
+		ss, err := queryCTL(l, c.query, c.maxBytes, c.state)
+		if nil != err {
+			return nil, err
+		}
+		c.queried.Add(l)
+		ns = append(ns, ss...)
+	}
+	return ns, nil
+}
+
+/* ctlLevels returns the levels of d to query, most-specific first.  If the
+levels are capped, it's logged, unless only d itself is queried, which is
+normal. */
+func (c crtshSource) ctlLevels(d string) []string {
+	rd, err := c.suffixes.EffectiveTLDPlusOne(d)
+	if nil != err {
+		return []string{d}
+	}
+	if 0 == c.levels {
+		if rd != d {
+			log.Printf(
+				"[%v] Only querying crt.sh for registrable "+
+					"domain %v",
+				d,
+				rd,
+			)
+		}
+		return []string{rd}
+	}
+
+	/* Walk up to the registrable domain */
+	ls := []string{d}
+	for n := d; n != rd && strings.HasSuffix(n, "."+rd); {
+		n = n[strings.Index(n, ".")+1:]
+		ls = append(ls, n)
+	}
+	if uint(len(ls)) <= c.levels {
+		return ls
+	}
+	if 1 == c.levels {
+		return ls[:1]
+	}
+	log.Printf(
+		"[%v] Only querying crt.sh for %v of %v levels, down to %v",
+		d,
+		c.levels,
+		len(ls),
+		ls[c.levels-1],
+	)
+	return ls[:c.levels]
+}
+
+/* String returns "crt.sh". */
+func (c crtshSource) String() string { return "crt.sh" }
+
+/* Source returns SourceCrtsh. */
+func (c crtshSource) Source() string { return SourceCrtsh }
+
+/* newCTLQuery checks that the crt.sh query string template t has exactly one
+%v, for the domain, and returns it with output=json added if it's not already
+there. */
+func newCTLQuery(t string) (string, error) {
+	if 1 != strings.Count(t, "%v") {
+		return "", fmt.Errorf(
+			"need exactly one %%v in query template %q",
+			t,
+		)
+	}
+	if strings.Contains(fmt.Sprintf(t, "x"), "%!") {
+		return "", fmt.Errorf("bad %% directive in %q", t)
+	}
+	if !strings.Contains(t, "output=") {
+		t += "&output=json"
+	}
+	return t, nil
+}
+
+/* queryCTL queries the CTL for subdomains of n, using the query string
+template query.  It returns an empty slice and no error if none were found.
+At most maxBytes bytes of the response are read; if the response is larger,
+the names found in the first maxBytes bytes are returned.  If state has
+validators from a previous identical query and crt.sh says nothing's changed,
+the names saved with them are returned. */
+func queryCTL(
+	n string,
+	query string,
+	maxBytes int64,
+	state *ctlState,
+) ([]string, error) {
+	/* Get JSON with more domains */
+	q := fmt.Sprintf(query, url.QueryEscape(n))
+	req, err := http.NewRequest("GET", CTLURL+q, nil)
+	if nil != err {
+		return nil, err
+	}
+	state.SetHeaders(req, q)
+	res, err := http.DefaultClient.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	/* 404's mean no names, 304's mean the same names as last time */
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		return []string{}, nil
+	case http.StatusNotModified:
+		log.Printf("[%v] crt.sh results unchanged", n)
+		if ns := state.Names(q); nil != ns {
+			return ns, nil
+		}
+		return []string{}, nil
+	}
+
+	/* Don't read more than we're allowed */
+	lr := &io.LimitedReader{R: res.Body, N: maxBytes}
+	br := bufio.NewReader(lr)
+
+	/* crt.sh sends either an array or a series of bare objects; the
+	decoder can handle either as long as it knows which. */
+	dec := json.NewDecoder(br)
+	b, err := firstNonSpace(br)
+	if io.EOF == err {
+		return []string{}, nil
+	} else if nil != err {
+		return nil, err
+	}
+	if '[' == b {
+		if _, err := dec.Token(); nil != err {
+			return nil, err
+		}
+	}
+
+	/* Decode names one at a time and dedupe */
+	var (
+		m         = make(map[string]struct{})
+		truncated bool
+	)
+	for dec.More() {
+		var c struct {
+			Name string `json:"name_value"`
+		}
+		if err := dec.Decode(&c); nil != err {
+			/* Running out of bytes isn't really an error */
+			if 0 == lr.N {
+				log.Printf(
+					"[%v] crt.sh response truncated "+
+						"after %v bytes, some "+
+						"subdomains may be missing",
+					n,
+					maxBytes,
+				)
+				truncated = true
+				break
+			}
+			return nil, err
+		}
+		if "" == c.Name {
+			continue
+		}
+		m[c.Name] = struct{}{}
+	}
+
+	/* Return names, remembering them if there's all of them */
+	ns := make([]string, 0, len(m))
+	for k := range m {
+		ns = append(ns, k)
+	}
+	if !truncated {
+		state.Update(q, res, ns)
+	}
+	return ns, nil
+}
+
+/* firstNonSpace skips leading whitespace in r and returns the first
+non-whitespace byte, which is left unread. */
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if nil != err {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+/*
+ * subdomains_test.go
+ * Tests for finding subdomains of names
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* funcSubdomainSource is a subdomainSource which calls a function. */
+type funcSubdomainSource struct {
+	name   string
+	source string
+	f      func(d string) []string
+}
+
+/* Subdomains calls s's function. */
+func (s funcSubdomainSource) Subdomains(d string) ([]string, error) {
+	return s.f(d), nil
+}
+
+/* String returns s's name. */
+func (s funcSubdomainSource) String() string { return s.name }
+
+/* Source returns s's candidate source. */
+func (s funcSubdomainSource) Source() string { return s.source }
+
+/* TestGetSubdomainNamesSlowSource is a load test which checks that while one
+domain's crt.sh query is stuck, checkers keep getting the other domains'
+names, as well as the subdomains other sources find. */
+func TestGetSubdomainNamesSlowSource(t *testing.T) {
+	const (
+		nDomain  = 1000
+		nChecker = 8
+		slow     = "slow.example.com"
+	)
+	var (
+		release = make(chan struct{})
+		ns      = make(chan string)
+		out     = make(chan string)
+		srcs    = []subdomainSource{funcSubdomainSource{
+			name:   "slow crt.sh",
+			source: SourceCrtsh,
+			f: func(d string) []string {
+				if slow == d {
+					<-release
+				}
+				return []string{"crtsh." + d}
+			},
+		}, funcSubdomainSource{
+			name:   "fast passive DNS",
+			source: SourcePassiveDNS,
+			f: func(d string) []string {
+				return []string{"pdns." + d}
+			},
+		}}
+	)
+	go getSubdomainNames(
+		context.Background(),
+		out,
+		ns,
+		srcs,
+		nil,
+		newNameSources(true),
+	)
+
+	/* Checkers, which note what they're asked to check */
+	var (
+		l    sync.Mutex
+		seen = make(map[string]struct{})
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < nChecker; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range out {
+				time.Sleep(10 * time.Microsecond) /* Checking */
+				l.Lock()
+				seen[n] = struct{}{}
+				l.Unlock()
+			}
+		}()
+	}
+	nSeen := func() int {
+		l.Lock()
+		defer l.Unlock()
+		return len(seen)
+	}
+
+	/* The slow domain first, then the rest */
+	ns <- slow
+	for i := 0; i < nDomain; i++ {
+		ns <- fmt.Sprintf("d%v.example.com", i)
+	}
+
+	/* Every name and passive DNS subdomain should be checked while the
+	slow query's stuck */
+	want := 2 * (nDomain + 1)
+	deadline := time.Now().Add(10 * time.Second)
+	for nSeen() < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := nSeen(); want != got {
+		t.Fatalf(
+			"Checked %v names during slow query, want %v",
+			got,
+			want,
+		)
+	}
+
+	/* Once it's done, everything else should come out */
+	close(release)
+	close(ns)
+	wg.Wait()
+	if got, want := nSeen(), 3*(nDomain+1); want != got {
+		t.Errorf("Checked %v names in all, want %v", got, want)
+	}
+	if _, ok := seen["crtsh."+slow]; !ok {
+		t.Errorf("Slow query's subdomain not checked")
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+/*
+ * subresource.go
+ * Learn more about public buckets
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Versioning statuses
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+	VersioningOff       = "Off"
+)
+
+// SUBRESOURCEMAXBODY is the maximum number of bytes of a subresource to read
+const SUBRESOURCEMAXBODY = 16 * 1024
+
+/* POLICYSUBRESOURCES are the subresources which say who may do what to a
+bucket, in the order in which they're reported. */
+var POLICYSUBRESOURCES = []string{"policy", "acl", "cors"}
+
+/* bucketInfo is what we could learn from a bucket's subresources.
+Subresources are often not readable even when the bucket is. */
+type bucketInfo struct {
+	/* versioning is one of the Versioning* constants, or the empty
+	string if it's not known */
+	versioning string
+
+	/* lifecycleRules is the number of lifecycle rules, or -1 if it's not
+	known */
+	lifecycleRules int
+
+	/* documents are the readable policy subresources, by name, possibly
+	truncated to SUBRESOURCEMAXBODY bytes */
+	documents map[string]string
+
+	/* owner is the bucket's owner, if it's known */
+	owner bucketOwner
+}
+
+/* String describes i, suitable for appending to a message. */
+func (i bucketInfo) String() string {
+	var ps []string
+	switch i.versioning {
+	case "":
+	case VersioningOff:
+		ps = append(ps, "versioning never enabled")
+	default:
+		ps = append(
+			ps,
+			"versioning "+strings.ToLower(i.versioning),
+		)
+	}
+	switch i.lifecycleRules {
+	case -1:
+	case 1:
+		ps = append(ps, "1 lifecycle rule")
+	default:
+		ps = append(ps, fmt.Sprintf(
+			"%v lifecycle rules",
+			i.lifecycleRules,
+		))
+	}
+	for _, sub := range POLICYSUBRESOURCES {
+		if _, ok := i.documents[sub]; ok {
+			ps = append(ps, "readable "+sub)
+		}
+	}
+	if "" != i.owner.ID {
+		ps = append(ps, "owner "+i.owner.String())
+	}
+	if 0 == len(ps) {
+		return ""
+	}
+	return " (" + strings.Join(ps, ", ") + ")"
+}
+
+/* getBucketInfo tries to read the versioning and lifecycle subresources of the
+bucket cand if conf.bucketInfo is set, and the policy subresources if
+conf.checkPolicy is set, using the same URL and Host as orig, the request which
+found the bucket.  Subresources which aren't readable are left as unknown. */
+func getBucketInfo(
+	cand candidate,
+	orig *http.Request,
+	worker uint,
+	conf *checkConfig,
+) bucketInfo {
+	info := bucketInfo{lifecycleRules: -1}
+	if conf.bucketInfo {
+		getLifecycleInfo(&info, cand, orig, worker, conf)
+	}
+	if !conf.checkPolicy {
+		return info
+	}
+
+	/* Policy documents are findings in themselves */
+	for _, sub := range POLICYSUBRESOURCES {
+		b, ok := getSubresource(cand, orig, sub, worker, conf)
+		if !ok || nil == b {
+			continue
+		}
+		if nil == info.documents {
+			info.documents = make(map[string]string)
+		}
+		info.documents[sub] = string(b)
+	}
+
+	/* A readable ACL says who owns the bucket */
+	if acl, ok := info.documents["acl"]; ok {
+		info.owner = ownerFromACL(acl)
+	}
+
+	return info
+}
+
+/* getLifecycleInfo fills in info's versioning and lifecycleRules, for
+getBucketInfo. */
+func getLifecycleInfo(
+	info *bucketInfo,
+	cand candidate,
+	orig *http.Request,
+	worker uint,
+	conf *checkConfig,
+) {
+	/* Versioning */
+	var v struct {
+		Status string
+	}
+	if ok := getXMLSubresource(
+		cand,
+		orig,
+		"versioning",
+		&v,
+		worker,
+		conf,
+	); ok {
+		switch v.Status {
+		case VersioningEnabled, VersioningSuspended:
+			info.versioning = v.Status
+		case "":
+			info.versioning = VersioningOff
+		default:
+			log.Printf(
+				"[%v] Unexpected versioning status %q",
+				cand.name,
+				v.Status,
+			)
+		}
+	}
+
+	/* Lifecycle */
+	var l struct {
+		Rule []struct{}
+	}
+	if ok := getXMLSubresource(
+		cand,
+		orig,
+		"lifecycle",
+		&l,
+		worker,
+		conf,
+	); ok {
+		info.lifecycleRules = len(l.Rule)
+	}
+}
+
+/* getXMLSubresource gets the subresource sub of the bucket cand, as
+getSubresource does, and unmarshals it into v.  It returns true if the
+subresource was unmarshalled or doesn't exist. */
+func getXMLSubresource(
+	cand candidate,
+	orig *http.Request,
+	sub string,
+	v interface{},
+	worker uint,
+	conf *checkConfig,
+) bool {
+	b, ok := getSubresource(cand, orig, sub, worker, conf)
+	if !ok || nil == b {
+		return ok
+	}
+	if err := xml.NewDecoder(bytes.NewReader(b)).Decode(v); nil != err {
+		elog.Printf("[%v] Error decoding %v: %v", cand.name, sub, err)
+		return false
+	}
+	return true
+}
+
+/* getSubresource gets the subresource sub of the bucket cand, using the same
+URL and Host as orig, and returns its first SUBRESOURCEMAXBODY bytes.  It
+returns true if the subresource was read or, with a nil slice, if the bucket
+says it doesn't have one.  Forbidden subresources are common and aren't
+logged. */
+func getSubresource(
+	cand candidate,
+	orig *http.Request,
+	sub string,
+	worker uint,
+	conf *checkConfig,
+) ([]byte, bool) {
+	n := cand.name
+
+	/* Roll the request */
+	u := *orig.URL
+	u.RawQuery = sub
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if nil != err {
+		elog.Printf("[%v] Unable to make %v request: %v", n, sub, err)
+		return nil, false
+	}
+	req.Host = orig.Host
+	req = req.WithContext(orig.Context())
+
+	/* See what the bucket says */
+	if !conf.wait(req.Context()) {
+		return nil, false
+	}
+	start := time.Now()
+	res, err := conf.client.Do(req)
+	lat := time.Since(start)
+	conf.requests.Add("subresource")
+	conf.latencies.Add(lat)
+	conf.trace.Trace(worker, n, req, res, err)
+	if nil != err {
+		if nil == req.Context().Err() {
+			elog.Printf("[%v] Error getting %v: %v", n, sub, err)
+		}
+		return nil, false
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, false
+	case http.StatusNotFound:
+		/* No configuration is still an answer */
+		switch readS3Error(res.Body).Code {
+		case "NoSuchLifecycleConfiguration",
+			"NoSuchBucketPolicy",
+			"NoSuchCORSConfiguration":
+			return nil, true
+		}
+		return nil, false
+	default:
+		log.Printf(
+			"[%v] Unexpected response getting %v: %v",
+			n,
+			sub,
+			res.Status,
+		)
+		return nil, false
+	}
+
+	/* Work out what it says */
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, SUBRESOURCEMAXBODY))
+	if nil != err {
+		elog.Printf("[%v] Error reading %v: %v", n, sub, err)
+		return nil, false
+	}
+	if nil == b {
+		b = []byte{}
+	}
+	return b, true
+}
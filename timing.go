@@ -0,0 +1,67 @@
+package main
+
+/*
+ * timing.go
+ * Keep track of how long requests take
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+/* latencyStats keeps track of the minimum, average, and maximum time requests
+take.  A nil *latencyStats ignores everything. */
+type latencyStats struct {
+	l     sync.Mutex
+	n     int
+	total time.Duration
+	min   time.Duration
+	max   time.Duration
+}
+
+/* Add records a request which took d. */
+func (s *latencyStats) Add(d time.Duration) {
+	if nil == s {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	if 0 == s.n || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.n++
+	s.total += d
+}
+
+/* Describe returns a human-readable description of d, suitable for appending
+to a message, or the empty string if s is nil. */
+func (s *latencyStats) Describe(d time.Duration) string {
+	if nil == s {
+		return ""
+	}
+	return fmt.Sprintf(" in %v", d.Round(time.Millisecond))
+}
+
+/* String returns a summary of the recorded latencies. */
+func (s *latencyStats) String() string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if 0 == s.n {
+		return "no requests made"
+	}
+	return fmt.Sprintf(
+		"min %v, avg %v, max %v over %v requests",
+		s.min.Round(time.Millisecond),
+		(s.total / time.Duration(s.n)).Round(time.Millisecond),
+		s.max.Round(time.Millisecond),
+		s.n,
+	)
+}
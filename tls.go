@@ -0,0 +1,116 @@
+package main
+
+/*
+ * tls.go
+ * TLS settings and checks
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// OLDTLSVERSION is the newest TLS version considered old enough to be worth
+// noting when negotiated with an endpoint
+const OLDTLSVERSION = tls.VersionTLS11
+
+/* TLSVERSIONS maps TLS version names to versions */
+var TLSVERSIONS = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/* newTLSConfig returns a tls.Config which requires at least the TLS version
+minVersion (e.g. 1.2) and, if ciphers isn't empty, uses only the cipher
+suites named in the comma-separated list ciphers.  TLS 1.3 cipher suites
+aren't configurable. */
+func newTLSConfig(minVersion, ciphers string) (*tls.Config, error) {
+	conf := &tls.Config{}
+
+	/* Work out the minimum version */
+	if "" != minVersion {
+		v, ok := TLSVERSIONS[minVersion]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown TLS version %q",
+				minVersion,
+			)
+		}
+		conf.MinVersion = v
+	}
+
+	/* Work out the allowed cipher suites */
+	if "" == ciphers {
+		return conf, nil
+	}
+	suites := make(map[string]uint16)
+	for _, cs := range append(
+		tls.CipherSuites(),
+		tls.InsecureCipherSuites()...,
+	) {
+		suites[cs.Name] = cs.ID
+	}
+	for _, n := range strings.Split(ciphers, ",") {
+		n = strings.TrimSpace(n)
+		if "" == n {
+			continue
+		}
+		id, ok := suites[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", n)
+		}
+		conf.CipherSuites = append(conf.CipherSuites, id)
+	}
+	return conf, nil
+}
+
+/* tlsVersionName returns a human-readable name for the TLS version v. */
+func tlsVersionName(v uint16) string {
+	for n, tv := range TLSVERSIONS {
+		if tv == v {
+			return "TLS " + n
+		}
+	}
+	return fmt.Sprintf("unknown TLS version 0x%04x", v)
+}
+
+/* oldTLSWarner logs when a host negotiates an old TLS version, once per
+host.  A nil *oldTLSWarner logs nothing.  It is safe to call oldTLSWarner's
+methods from multiple goroutines. */
+type oldTLSWarner struct {
+	l    sync.Mutex
+	seen map[string]struct{}
+}
+
+/* newOldTLSWarner returns a new oldTLSWarner. */
+func newOldTLSWarner() *oldTLSWarner {
+	return &oldTLSWarner{seen: make(map[string]struct{})}
+}
+
+/* Check logs a message if the connection state cs, to the host h, has a TLS
+version no newer than OLDTLSVERSION and h hasn't been logged before.  cs may
+be nil for plaintext connections. */
+func (w *oldTLSWarner) Check(h string, cs *tls.ConnectionState) {
+	if nil == w || nil == cs || OLDTLSVERSION < cs.Version {
+		return
+	}
+	w.l.Lock()
+	defer w.l.Unlock()
+	if _, ok := w.seen[h]; ok {
+		return
+	}
+	w.seen[h] = struct{}{}
+	log.Printf(
+		"Endpoint %v negotiated an old TLS version: %v",
+		h,
+		tlsVersionName(cs.Version),
+	)
+}
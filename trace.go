@@ -0,0 +1,151 @@
+package main
+
+/*
+ * trace.go
+ * Log requests and responses for debugging
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TRACEMAXBODY is the maximum number of bytes of a response body recorded in
+// the trace
+const TRACEMAXBODY = 1024 * 1024
+
+// TRACEHEADERS are the response headers recorded in the trace
+var TRACEHEADERS = []string{
+	"Content-Type",
+	"Location",
+	"Server",
+	"X-Amz-Bucket-Region",
+	"X-Amz-Id-2",
+	"X-Amz-Request-Id",
+}
+
+/* traceRecord is a single request and its response, as written to the trace
+file.  The first TRACEMAXBODY bytes of every response body are recorded, be
+it a listing, an error document, or a subresource, so the check can be
+replayed; BodyTruncated is set if there was more. */
+type traceRecord struct {
+	Time    time.Time         `json:"time"`
+	Worker  uint              `json:"worker"`
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Host    string            `json:"host"`
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Error   string            `json:"error,omitempty"`
+
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+}
+
+/* tracer writes a JSON line for every request made.  A nil *tracer discards
+everything written to it. */
+type tracer struct {
+	l sync.Mutex
+	f *os.File
+	e *json.Encoder
+}
+
+/* newTracer returns a tracer which writes to the file named fn, which will be
+truncated if it exists.  If fn is the empty string, newTracer returns nil. */
+func newTracer(fn string) (*tracer, error) {
+	if "" == fn {
+		return nil, nil
+	}
+	f, err := os.Create(fn)
+	if nil != err {
+		return nil, err
+	}
+	return &tracer{f: f, e: json.NewEncoder(f)}, nil
+}
+
+/* Trace records req made by the numbered worker to check the name n and
+either its response, res, or the error, err, which resulted.  If res's body
+is recorded, it is replaced with an equivalent reader. */
+func (t *tracer) Trace(
+	worker uint,
+	n string,
+	req *http.Request,
+	res *http.Response,
+	err error,
+) {
+	if nil == t {
+		return
+	}
+
+	/* Roll the record */
+	r := traceRecord{
+		Time:   time.Now(),
+		Worker: worker,
+		Name:   n,
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Host:   req.Host,
+	}
+	if nil != err {
+		r.Error = err.Error()
+	}
+	if nil != res {
+		r.Status = res.StatusCode
+		for _, h := range TRACEHEADERS {
+			v := res.Header.Get(h)
+			if "" == v {
+				continue
+			}
+			if nil == r.Headers {
+				r.Headers = make(map[string]string)
+			}
+			r.Headers[h] = v
+		}
+		/* Record the body, and put it back for the check */
+		b, _ := ioutil.ReadAll(io.LimitReader(
+			res.Body,
+			TRACEMAXBODY+1,
+		))
+		if TRACEMAXBODY < len(b) {
+			r.Body = string(b[:TRACEMAXBODY])
+			r.BodyTruncated = true
+		} else {
+			r.Body = string(b)
+		}
+		res.Body = readCloser{
+			io.MultiReader(bytes.NewReader(b), res.Body),
+			res.Body,
+		}
+	}
+
+	/* Write it out */
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.e.Encode(r) /* Not much to do on error */
+}
+
+/* Close closes the trace file. */
+func (t *tracer) Close() error {
+	if nil == t {
+		return nil
+	}
+	t.l.Lock()
+	defer t.l.Unlock()
+	return t.f.Close()
+}
+
+/* readCloser combines a Reader with a different Closer. */
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
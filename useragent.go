@@ -0,0 +1,38 @@
+package main
+
+/*
+ * useragent.go
+ * Say who's asking
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import "net/http"
+
+// USERAGENT is the default User-Agent header sent with requests
+const USERAGENT = "s3finder (+https://github.com/magisterquis/s3finder)"
+
+/* userAgentTransport is an http.RoundTripper which sets the User-Agent header
+on requests which don't already have one before sending them with rt.  If ua
+is the empty string, requests are sent without a User-Agent header, rather
+than with Go's. */
+type userAgentTransport struct {
+	rt http.RoundTripper
+	ua string
+}
+
+/* RoundTrip sends req with t's User-Agent. */
+func (t userAgentTransport) RoundTrip(req *http.Request) (
+	*http.Response,
+	error,
+) {
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req = req.Clone(req.Context())
+		if nil == req.Header {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set("User-Agent", t.ua)
+	}
+	return t.rt.RoundTrip(req)
+}
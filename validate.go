@@ -0,0 +1,65 @@
+package main
+
+/*
+ * validate.go
+ * Check names without scanning
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/* validateNames writes to w whether each name on ns is a valid bucket name
+under rules, as it would be normalized before checking.  Each line is one of
+
+	valid<TAB>name
+	normalized<TAB>name<TAB>normalized name
+	rejected<TAB>name<TAB>reason
+
+Names which are normalized to an invalid name are rejected, with the reason
+including the normalized name.  It returns the number of valid, normalized,
+and rejected names. */
+func validateNames(
+	w io.Writer,
+	ns <-chan string,
+	rules namingRules,
+) (nValid, nNormalized, nRejected int, err error) {
+	for n := range ns {
+		/* Skip the same things as processNames */
+		n = strings.TrimSpace(n)
+		if "" == n || strings.HasPrefix(n, "#") {
+			continue
+		}
+
+		/* Same as processName */
+		norm := rules.normalize(n)
+
+		var l string
+		if p := rules.problem(norm); "" != p {
+			if norm != n {
+				p = fmt.Sprintf(
+					"%v (normalized to %q)",
+					p,
+					norm,
+				)
+			}
+			l = fmt.Sprintf("rejected\t%v\t%v", n, p)
+			nRejected++
+		} else if norm != n {
+			l = fmt.Sprintf("normalized\t%v\t%v", n, norm)
+			nNormalized++
+		} else {
+			l = fmt.Sprintf("valid\t%v", n)
+			nValid++
+		}
+		if _, err := fmt.Fprintln(w, l); nil != err {
+			return nValid, nNormalized, nRejected, err
+		}
+	}
+	return nValid, nNormalized, nRejected, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+/*
+ * webui.go
+ * Watch a run from a browser
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WEBRECENT is the number of recent findings the web UI shows
+const WEBRECENT = 100
+
+/* webAssets are the web UI's static files. */
+//go:embed web
+var webAssets embed.FS
+
+/* webServer is a resultSink which serves a dashboard showing what the run's
+been up to, with its recent findings.  Names which aren't buckets aren't
+kept.  It is safe to call webServer's methods from multiple goroutines. */
+type webServer struct {
+	ln    net.Listener
+	start time.Time
+
+	l        sync.Mutex
+	recent   []Result /* Ring of WEBRECENT results */
+	next     int      /* Next index in recent */
+	findings map[string]uint
+	conf     *checkConfig
+	certs    *certStreamStatus
+}
+
+/* newWebServer listens on addr for HTTP requests, which aren't served until
+Serve is called.  If addr is the empty string, newWebServer returns nil. */
+func newWebServer(addr string, start time.Time) (*webServer, error) {
+	if "" == addr {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if nil != err {
+		return nil, err
+	}
+	return &webServer{
+		ln:       ln,
+		start:    start,
+		recent:   make([]Result, 0, WEBRECENT),
+		findings: make(map[string]uint),
+	}, nil
+}
+
+/* Send notes r, unless it's not a bucket. */
+func (w *webServer) Send(r Result) {
+	if StatusNotBucket == r.Status {
+		return
+	}
+	w.l.Lock()
+	defer w.l.Unlock()
+	w.findings[r.Status]++
+	if len(w.recent) < WEBRECENT {
+		w.recent = append(w.recent, r)
+	} else {
+		w.recent[w.next] = r
+	}
+	w.next = (w.next + 1) % WEBRECENT
+}
+
+/* Serve starts serving the dashboard, with stats from conf and certs, which
+may be nil if the certificate stream isn't being watched. */
+func (w *webServer) Serve(conf *checkConfig, certs *certStreamStatus) {
+	w.l.Lock()
+	w.conf = conf
+	w.certs = certs
+	w.l.Unlock()
+
+	sub, err := fs.Sub(webAssets, "web")
+	if nil != err {
+		log.Panicf("Web UI assets missing: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(sub)))
+	mux.HandleFunc("/stats.json", w.handleStats)
+	log.Printf("Serving web UI on http://%v", w.ln.Addr())
+	go func() {
+		err := http.Serve(w.ln, mux)
+		if nil != err && !errors.Is(err, net.ErrClosed) {
+			elog.Printf("Error serving web UI: %v", err)
+		}
+	}()
+}
+
+/* handleStats sends the run's stats as JSON. */
+func (w *webServer) handleStats(rw http.ResponseWriter, req *http.Request) {
+	w.l.Lock()
+	s := struct {
+		Start        time.Time        `json:"start"`
+		Requests     map[string]uint  `json:"requests"`
+		Unexpected   map[string]uint  `json:"unexpected"`
+		Findings     map[string]uint  `json:"findings"`
+		Coalesced    uint64           `json:"coalesced"`
+		Suppressed   string           `json:"suppressed"`
+		AdaptiveRate string           `json:"adaptive_rate,omitempty"`
+		CertStream   certStreamCounts `json:"certstream"`
+		Recent       []Result         `json:"recent"`
+	}{
+		Start:      w.start,
+		Requests:   w.conf.requests.Counts(),
+		Unexpected: w.conf.unexpected.Counts(),
+		Findings:   make(map[string]uint, len(w.findings)),
+		Coalesced:  w.conf.inFlight.Coalesced(),
+		Suppressed: w.conf.ignore.String(),
+		CertStream: w.certs.Counts(),
+		Recent:     make([]Result, 0, len(w.recent)),
+	}
+	for k, v := range w.findings {
+		s.Findings[k] = v
+	}
+	/* Newest first */
+	for i := 1; i <= len(w.recent); i++ {
+		s.Recent = append(
+			s.Recent,
+			w.recent[(w.next-i+len(w.recent))%len(w.recent)],
+		)
+	}
+	w.l.Unlock()
+	if nil != w.conf.adaptive {
+		s.AdaptiveRate = w.conf.adaptive.String()
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(s); nil != err {
+		elog.Printf(
+			"Error sending stats to %v: %v",
+			req.RemoteAddr,
+			err,
+		)
+	}
+}
+
+/* Close stops serving the dashboard. */
+func (w *webServer) Close() error {
+	if nil == w {
+		return nil
+	}
+	return w.ln.Close()
+}
+
+/* certStreamStatus keeps track of the certificate stream.  A nil
+*certStreamStatus keeps track of nothing.  It is safe to call
+certStreamStatus's methods from multiple goroutines. */
+type certStreamStatus struct {
+	l sync.Mutex
+	c certStreamCounts
+}
+
+/* certStreamCounts is what a certStreamStatus knows. */
+type certStreamCounts struct {
+	Enabled   bool       `json:"enabled"`
+	Connected bool       `json:"connected"`
+	Certs     uint       `json:"certs"`
+	Names     uint       `json:"names"`
+	LastCert  *time.Time `json:"last_cert,omitempty"`
+}
+
+/* newCertStreamStatus returns a new certStreamStatus, or nil if enabled is
+false. */
+func newCertStreamStatus(enabled bool) *certStreamStatus {
+	if !enabled {
+		return nil
+	}
+	return &certStreamStatus{c: certStreamCounts{Enabled: true}}
+}
+
+/* SetConnected notes whether the stream is connected. */
+func (s *certStreamStatus) SetConnected(connected bool) {
+	if nil == s {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.c.Connected = connected
+}
+
+/* Cert notes a certificate with n names. */
+func (s *certStreamStatus) Cert(n int) {
+	if nil == s {
+		return
+	}
+	now := time.Now()
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.c.Certs++
+	s.c.Names += uint(n)
+	s.c.LastCert = &now
+}
+
+/* Counts returns what s knows. */
+func (s *certStreamStatus) Counts() certStreamCounts {
+	if nil == s {
+		return certStreamCounts{}
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.c
+}
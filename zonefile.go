@@ -0,0 +1,148 @@
+package main
+
+/*
+ * zonefile.go
+ * Get names from DNS zone files
+ * By J. Stuart McMurray
+ * Created 20261014
+ * Last Modified 20261014
+ */
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+/* ZONENAMETYPES are the types of records whose names are taken from zone
+files. */
+var ZONENAMETYPES = map[string]struct{}{
+	"A":     {},
+	"AAAA":  {},
+	"CNAME": {},
+}
+
+/* ZONECLASSES are the DNS classes which may appear before a record's type. */
+var ZONECLASSES = map[string]struct{}{
+	"IN": {},
+	"CH": {},
+	"HS": {},
+	"CS": {},
+}
+
+/* namesFromZoneFile sends the names of the A, AAAA, and CNAME records in the
+BIND-format zone file named n, such as the output of an AXFR, to c.  Relative
+names are made absolute with the zone file's $ORIGIN, if it has one.
+Wildcard records and other types of records are skipped. */
+func namesFromZoneFile(c chan<- string, n string) error {
+	f, err := os.Open(n)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		origin string
+		owner  string
+		sent   = make(map[string]struct{})
+		buf    string
+		depth  int
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		/* Records in parentheses may span lines */
+		l, d := stripZoneComment(scanner.Text())
+		depth += d
+		if 0 < depth {
+			buf += l + " "
+			continue
+		}
+		l, buf, depth = buf+l, "", 0
+
+		/* Directives and blank lines */
+		fs := strings.Fields(l)
+		if 0 == len(fs) {
+			continue
+		}
+		if strings.HasPrefix(fs[0], "$") {
+			if "$ORIGIN" == strings.ToUpper(fs[0]) && 2 <= len(fs) {
+				origin = strings.TrimSuffix(fs[1], ".")
+			}
+			continue
+		}
+
+		/* Lines which start with a space have the previous owner */
+		if !unicode.IsSpace(rune(l[0])) {
+			owner = zoneName(fs[0], origin)
+			fs = fs[1:]
+		}
+
+		/* Skip the TTL and class to get to the type */
+		for 0 < len(fs) {
+			_, isClass := ZONECLASSES[strings.ToUpper(fs[0])]
+			if !isClass && !unicode.IsDigit(rune(fs[0][0])) {
+				break
+			}
+			fs = fs[1:]
+		}
+		if 0 == len(fs) {
+			continue
+		}
+		if _, ok := ZONENAMETYPES[strings.ToUpper(fs[0])]; !ok {
+			continue
+		}
+
+		/* Got a name */
+		if "" == owner || strings.Contains(owner, "*") {
+			continue
+		}
+		if _, ok := sent[owner]; ok {
+			continue
+		}
+		sent[owner] = struct{}{}
+		c <- owner
+	}
+	return scanner.Err()
+}
+
+/* zoneName returns the name n from a zone file with the origin o, without a
+trailing dot. */
+func zoneName(n, o string) string {
+	switch {
+	case "@" == n:
+		return o
+	case strings.HasSuffix(n, "."):
+		return strings.TrimSuffix(n, ".")
+	case "" == o:
+		return n
+	default:
+		return n + "." + o
+	}
+}
+
+/* stripZoneComment removes a comment from a line from a zone file, taking
+care not to mistake a semicolon in a quoted string for one.  It also returns
+the number of opening less closing parentheses outside of quotes. */
+func stripZoneComment(l string) (string, int) {
+	var (
+		quoted bool
+		depth  int
+	)
+	for i, r := range l {
+		if quoted && '"' != r {
+			continue
+		}
+		switch r {
+		case '"':
+			quoted = !quoted
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';':
+			return l[:i], depth
+		}
+	}
+	return l, depth
+}